@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/tui"
+	"github.com/toyz/ssh-thing/vault"
+	"golang.org/x/term"
+)
+
+const configUsage = `Usage:
+  ssh-thing config validate [file]      Check a servers file and list every problem found
+  ssh-thing config convert <from> <to>  Convert a servers or keybinds file to the format of
+                                        the new file's extension: .toml, .yaml, .yml or .json
+  ssh-thing config migrate [file]       Upgrade the servers and keybinds files to the current
+                                        version, keeping a backup of each
+  ssh-thing config schema <kind>        Print the JSON Schema of the servers or keybinds file`
+
+// runConfigCommand runs a "config" subcommand and returns the exit code.
+func runConfigCommand(args []string, serversPath, keybindsPath string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "validate":
+		if len(args) > 1 {
+			serversPath = args[1]
+		}
+		return validateConfig(serversPath)
+	case "convert":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, configUsage)
+			return 2
+		}
+		if err := config.ConvertFile(args[1], args[2]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Converted %s to %s\n", args[1], args[2])
+		return 0
+	case "migrate":
+		if len(args) > 1 {
+			serversPath = args[1]
+		}
+		return migrateConfig(serversPath, keybindsPath)
+	case "schema":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, configUsage)
+			return 2
+		}
+		return printSchema(args[1])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config command %q\n%s\n", args[0], configUsage)
+		return 2
+	}
+}
+
+func validateConfig(serversPath string) int {
+	cfg, err := config.LoadConfig(serversPath)
+
+	var invalid *config.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		for _, problem := range invalid.Problems {
+			fmt.Println(problem)
+		}
+		fmt.Fprintln(os.Stderr, countProblems(invalid.Problems))
+		return 1
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, warning := range cfg.Warnings {
+		fmt.Println(warning)
+	}
+	if len(cfg.Warnings) > 0 {
+		fmt.Printf("%s: %d servers, %s\n", cfg.Path, len(cfg.Servers), countProblems(cfg.Warnings))
+		return 0
+	}
+	fmt.Printf("%s: %d servers, no problems found\n", cfg.Path, len(cfg.Servers))
+	return 0
+}
+
+// countProblems summarizes how many errors and warnings were found.
+func countProblems(problems []config.Problem) string {
+	var errs, warnings int
+	for _, problem := range problems {
+		if problem.Warning {
+			warnings++
+		} else {
+			errs++
+		}
+	}
+
+	var counts []string
+	for _, count := range []struct {
+		n    int
+		noun string
+	}{{errs, "problem"}, {warnings, "warning"}} {
+		switch {
+		case count.n == 1:
+			counts = append(counts, "1 "+count.noun)
+		case count.n > 1:
+			counts = append(counts, fmt.Sprintf("%d %ss", count.n, count.noun))
+		}
+	}
+	return strings.Join(counts, " and ") + " found"
+}
+
+// migrateConfig upgrades the servers files and the keybinds file to the
+// current version and returns the exit code.
+func migrateConfig(serversPath, keybindsPath string) int {
+	cfg, err := config.LoadConfig(serversPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "Fix the problems above before migrating")
+		return 1
+	}
+
+	code := 0
+	upgrade := func(migrations config.Migrations, file string) {
+		version, backup, err := migrations.Upgrade(file)
+		switch {
+		case err != nil:
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		case backup == "":
+			fmt.Printf("%s: already at version %d\n", file, version)
+		default:
+			fmt.Printf("%s: upgraded from version %d to %d, backup at %s\n", file, version, migrations.Current(), backup)
+		}
+	}
+
+	for _, file := range cfg.Files() {
+		upgrade(config.ServerMigrations, file)
+	}
+	if keybinds := tui.KeybindsPath(keybindsPath); keybinds != "" {
+		if _, err := os.Stat(keybinds); err == nil {
+			upgrade(tui.KeybindsMigrations, keybinds)
+		}
+	}
+	return code
+}
+
+// printSchema prints the JSON Schema of a kind of config file and returns
+// the exit code.
+func printSchema(kind string) int {
+	var schema []byte
+	var err error
+	switch kind {
+	case "servers":
+		schema, err = config.JSONSchema(config.Config{}, "ssh-thing servers file", config.ServerMigrations.Current())
+	case "keybinds":
+		schema, err = config.JSONSchema(tui.KeyBindingsConfig{}, "ssh-thing keybinds file", tui.KeybindsMigrations.Current())
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown kind of config file %q, expected servers or keybinds\n", kind)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout.Write(schema)
+	return 0
+}
+
+// printPaths prints the directories config files are looked for in and the
+// files that are loaded from them, and returns the exit code.
+func printPaths(serversPath, keybindsPath string) int {
+	fmt.Println("Search path:")
+	for _, dir := range config.SearchDirs() {
+		fmt.Println("  " + absPath(dir))
+	}
+	fmt.Println()
+
+	code := 0
+	cfg, err := config.LoadConfig(serversPath)
+	switch {
+	case err == nil:
+		for _, file := range cfg.Files() {
+			printPath("servers", file)
+		}
+	case serversPath != "":
+		printPath("servers", serversPath)
+		fallthrough
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+
+	printPath("keybinds", tui.KeybindsPath(keybindsPath))
+
+	sessionPath, err := tui.SessionPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printPath("session", sessionPath)
+	printPath("vault", tui.VaultPath())
+	return code
+}
+
+func printPath(kind, path string) {
+	path = absPath(path)
+	if _, err := os.Stat(path); err != nil {
+		path += " (not created yet)"
+	}
+	fmt.Printf("%-9s %s\n", kind, path)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+const vaultUsage = `Usage:
+  ssh-thing vault set <name>      Store a password or passphrase, creating the vault if needed
+  ssh-thing vault list            List the names of the stored secrets
+  ssh-thing vault remove <name>   Remove a secret
+
+Servers refer to a stored secret as password = "vault:<name>".`
+
+// runVaultCommand runs a "vault" subcommand and returns the exit code.
+func runVaultCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, vaultUsage)
+		return 2
+	}
+
+	store := vault.New(tui.VaultPath())
+	if store.Path() == "" {
+		fmt.Fprintln(os.Stderr, "Failed to find the config directory for the vault")
+		return 1
+	}
+
+	var err error
+	switch {
+	case args[0] == "set" && len(args) == 2:
+		err = setVaultSecret(store, args[1])
+	case args[0] == "list" && len(args) == 1:
+		err = listVaultSecrets(store)
+	case args[0] == "remove" && len(args) == 2:
+		if err = unlockVault(store); err == nil {
+			err = store.Delete(args[1])
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown vault command %q\n%s\n", strings.Join(args, " "), vaultUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func setVaultSecret(store *vault.Store, name string) error {
+	if !store.Exists() {
+		fmt.Fprintf(os.Stderr, "Creating a new vault at %s\n", store.Path())
+		passphrase, err := readSecret("New vault passphrase: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := readSecret("Repeat the passphrase: ")
+		if err != nil {
+			return err
+		}
+		if passphrase == "" || passphrase != confirm {
+			return fmt.Errorf("the passphrases are empty or don't match")
+		}
+		if err := store.Create(passphrase); err != nil {
+			return err
+		}
+	} else if err := unlockVault(store); err != nil {
+		return err
+	}
+
+	secret, err := readSecret("Secret for " + name + ": ")
+	if err != nil {
+		return err
+	}
+	if err := store.Set(name, secret); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Stored %s; use it as \"%s%s\"\n", name, config.VaultPrefix, name)
+	return nil
+}
+
+func listVaultSecrets(store *vault.Store) error {
+	if !store.Exists() {
+		return fmt.Errorf("no vault at %s", store.Path())
+	}
+	if err := unlockVault(store); err != nil {
+		return err
+	}
+
+	for _, name := range store.Names() {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func unlockVault(store *vault.Store) error {
+	passphrase, err := readSecret("Vault passphrase: ")
+	if err != nil {
+		return err
+	}
+	return store.Unlock(passphrase)
+}
+
+// readSecret prompts for a secret without echoing it. When stdin is not a
+// terminal, a line is read from it instead so the vault can be scripted.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read %s%w", strings.ToLower(prompt), err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s%w", strings.ToLower(prompt), err)
+	}
+	return string(secret), nil
+}
+
+var stdin = bufio.NewReader(os.Stdin)
@@ -0,0 +1,539 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Extensions are the extensions config files are recognized by, in the
+// order they are looked for. Files with any other extension are read as
+// TOML.
+var Extensions = []string{".toml", ".yaml", ".yml", ".json"}
+
+// FileNames returns the names a config file called base is looked for
+// under, one per extension.
+func FileNames(base string) []string {
+	names := make([]string, len(Extensions))
+	for i, ext := range Extensions {
+		names[i] = base + ext
+	}
+	return names
+}
+
+// codec is the format of a config file.
+type codec int
+
+const (
+	codecTOML codec = iota
+	codecYAML
+	codecJSON
+)
+
+func codecFor(path string) codec {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return codecYAML
+	case ".json":
+		return codecJSON
+	}
+	return codecTOML
+}
+
+// unmarshalFile decodes a config file in the format picked by the extension
+// of path into v, as toml.Unmarshal would.
+func unmarshalFile(path string, data []byte, v any) error {
+	if codecFor(path) == codecTOML {
+		return toml.Unmarshal(data, v)
+	}
+
+	doc, err := parseFile(path, data)
+	if err != nil {
+		return err
+	}
+	return toml.Unmarshal(doc.Bytes(), v)
+}
+
+// Marshal encodes v, which is marshaled with its toml tags, in the format
+// picked by the extension of path, keeping the order of its fields.
+func Marshal(path string, v any) ([]byte, error) {
+	data, err := toml.Marshal(v)
+	if err != nil || codecFor(path) == codecTOML {
+		return data, err
+	}
+
+	return encodeFile(path, nil, ParseDocument(data))
+}
+
+// ConvertFile writes the config file from to the new file to, in the format
+// picked by to's extension. Keys keep their order, and YAML comments are
+// kept when converting to YAML; included files are not converted.
+func ConvertFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", from, err)
+	}
+	if _, err := os.Stat(to); err == nil {
+		return fmt.Errorf("%s already exists", to)
+	}
+
+	doc, err := parseFile(from, data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", from, err)
+	}
+	if codecFor(from) == codecTOML {
+		// Only YAML and JSON sources are merged into, so start afresh.
+		data = nil
+	}
+	converted, err := encodeFile(to, data, doc)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s: %w", from, err)
+	}
+	return writeConfigFile(to, converted)
+}
+
+// syntaxError is a YAML or JSON file that could not be parsed.
+type syntaxError struct {
+	line    int
+	message string
+}
+
+func (e *syntaxError) Error() string {
+	if e.line == 0 {
+		return e.message
+	}
+	return fmt.Sprintf("line %d: %s", e.line, e.message)
+}
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// parseFile parses a config file into a Document. YAML and JSON files are
+// converted to the equivalent TOML, so they are decoded, validated and
+// edited the same way; the problems found in them are reported at the
+// position of the YAML or JSON they came from.
+func parseFile(path string, data []byte) (*Document, error) {
+	if codecFor(path) == codecTOML {
+		return ParseDocument(data), nil
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		message := strings.TrimPrefix(err.Error(), "yaml: ")
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, &syntaxError{line: line, message: m[2]}
+		}
+		return nil, &syntaxError{message: message}
+	}
+
+	w := &tomlWriter{}
+	if len(root.Content) > 0 {
+		node := resolveAlias(root.Content[0])
+		if node.Kind != yaml.MappingNode {
+			return nil, &syntaxError{line: node.Line, message: "expected a mapping of keys at the top level"}
+		}
+		if err := w.table(nil, node); err != nil {
+			return nil, err
+		}
+	}
+	return &Document{lines: w.lines, origins: w.origins}, nil
+}
+
+// position is a 1-based line and column in a file.
+type position struct {
+	line, column int
+}
+
+// tomlWriter writes the TOML equivalent of a YAML node, remembering the
+// position each line came from.
+type tomlWriter struct {
+	lines   []string
+	origins []position
+}
+
+func (w *tomlWriter) add(text string, from *yaml.Node) {
+	for _, line := range strings.Split(text, "\n") {
+		w.lines = append(w.lines, line)
+		w.origins = append(w.origins, position{line: from.Line, column: from.Column})
+	}
+}
+
+// table writes the values of a mapping as key/value pairs, followed by the
+// mappings and lists of mappings in it as tables and arrays of tables.
+func (w *tomlWriter) table(path []string, node *yaml.Node) error {
+	var nested [][2]*yaml.Node
+	for _, pair := range mappingPairs(node) {
+		key, value := pair[0], pair[1]
+		switch {
+		case value.Kind == yaml.MappingNode, isTableList(value):
+			nested = append(nested, pair)
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			// TOML has no null; a key without a value is left out.
+		default:
+			var decoded any
+			if err := value.Decode(&decoded); err != nil {
+				return &syntaxError{line: value.Line, message: err.Error()}
+			}
+			encoded, err := encodeValue(decoded)
+			if err != nil {
+				return &syntaxError{line: value.Line, message: fmt.Sprintf("%s: %v", key.Value, err)}
+			}
+			w.add(tomlKey(key.Value)+" = "+encoded, key)
+		}
+	}
+
+	for _, pair := range nested {
+		key, value := pair[0], pair[1]
+		sub := append(path[:len(path):len(path)], key.Value)
+		header := make([]string, len(sub))
+		for i, name := range sub {
+			header[i] = tomlKey(name)
+		}
+
+		if value.Kind == yaml.MappingNode {
+			w.add("["+strings.Join(header, ".")+"]", key)
+			if err := w.table(sub, value); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, item := range value.Content {
+			item = resolveAlias(item)
+			w.add("[["+strings.Join(header, ".")+"]]", item)
+			if err := w.table(sub, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mappingPairs returns the keys and values of a mapping with aliases
+// resolved and merge keys (<<) expanded. Keys set in the mapping itself
+// override merged ones.
+func mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	node = resolveAlias(node)
+
+	var pairs [][2]*yaml.Node
+	index := make(map[string]int)
+	set := func(key, value *yaml.Node) {
+		if i, ok := index[key.Value]; ok {
+			pairs[i] = [2]*yaml.Node{key, value}
+			return
+		}
+		index[key.Value] = len(pairs)
+		pairs = append(pairs, [2]*yaml.Node{key, value})
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+		if key.Tag != "!!merge" {
+			continue
+		}
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, m := range merged {
+			for _, pair := range mappingPairs(m) {
+				set(pair[0], pair[1])
+			}
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; key.Tag != "!!merge" {
+			set(key, resolveAlias(node.Content[i+1]))
+		}
+	}
+	return pairs
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// isTableList reports whether node is a non-empty list of mappings, which
+// is written as an array of tables.
+func isTableList(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return false
+	}
+	for _, item := range node.Content {
+		if resolveAlias(item).Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+// encodeFile returns the contents of a config file after doc, parsed from
+// data, was edited. YAML and JSON files are updated from the edited TOML
+// rather than rewritten, so their key order and YAML comments are kept.
+// Without data, the file is written from doc alone.
+func encodeFile(path string, data []byte, doc *Document) ([]byte, error) {
+	if codecFor(path) == codecTOML {
+		return doc.Bytes(), nil
+	}
+
+	var value map[string]any
+	if err := toml.Unmarshal(doc.Bytes(), &value); err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.DocumentNode {
+		root = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	var top *yaml.Node
+	if len(root.Content) > 0 {
+		top = root.Content[0]
+	}
+	root.Content = []*yaml.Node{mergeNode(top, value, tableOrder(doc), "")}
+	return encodeNode(path, &root)
+}
+
+// tableOrder lists the keys of each table of doc in the order they appear,
+// including the tables nested in it. Tables are named by their dotted path,
+// with the index of each table in an array of tables, e.g. servers.2.
+func tableOrder(doc *Document) map[string][]string {
+	order := make(map[string][]string)
+	add := func(path, key string) string {
+		if !slices.Contains(order[path], key) {
+			order[path] = append(order[path], key)
+		}
+		return joinPath(path, key)
+	}
+
+	arrays := make(map[string]int)
+	table := ""
+	for _, stmt := range doc.statements() {
+		switch stmt.kind {
+		case stmtTable:
+			keys := strings.Split(stmt.name, ".")
+			table = ""
+			for i, key := range keys {
+				table = add(table, key)
+				index, isArray := arrays[table]
+				if stmt.array && i == len(keys)-1 {
+					if !isArray {
+						index = -1
+					}
+					index++
+					arrays[table] = index
+					isArray = true
+				}
+				if isArray {
+					table = joinPath(table, strconv.Itoa(index))
+				}
+			}
+		case stmtKeyValue:
+			path := table
+			for _, key := range strings.Split(stmt.name, ".") {
+				path = add(path, key)
+			}
+		}
+	}
+	return order
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// mergeNode updates node, at path in the file, to hold value, which was
+// decoded from TOML. Keys and list items that are still there keep their
+// node, and with it their position and comments; new keys are added in the
+// order order lists them in.
+func mergeNode(node *yaml.Node, value any, order map[string][]string, path string) *yaml.Node {
+	if node != nil {
+		node = resolveAlias(node)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if node == nil || node.Kind != yaml.MappingNode {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		pairs := mappingPairs(node)
+		seen := make(map[string]bool)
+		for _, pair := range pairs {
+			seen[pair[0].Value] = true
+		}
+		var added []string
+		for key := range v {
+			if !seen[key] {
+				added = append(added, key)
+			}
+		}
+		rank := func(key string) int {
+			if i := slices.Index(order[path], key); i >= 0 {
+				return i
+			}
+			return len(order[path])
+		}
+		sort.Slice(added, func(i, j int) bool {
+			if ri, rj := rank(added[i]), rank(added[j]); ri != rj {
+				return ri < rj
+			}
+			return added[i] < added[j]
+		})
+
+		// New keys go before the first existing key that comes after them.
+		var content []*yaml.Node
+		addUntil := func(limit int) {
+			for len(added) > 0 && rank(added[0]) < limit {
+				key := added[0]
+				added = added[1:]
+				keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+				content = append(content, keyNode, mergeNode(nil, v[key], order, joinPath(path, key)))
+			}
+		}
+		for _, pair := range pairs {
+			key, value := pair[0], pair[1]
+			if item, ok := v[key.Value]; ok {
+				addUntil(rank(key.Value))
+				content = append(content, key, mergeNode(value, item, order, joinPath(path, key.Value)))
+			}
+		}
+		addUntil(len(order[path]) + 1)
+		node.Content = content
+		return node
+
+	case []any:
+		if node == nil || node.Kind != yaml.SequenceNode {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		content := make([]*yaml.Node, len(v))
+		for i, item := range v {
+			var existing *yaml.Node
+			if i < len(node.Content) {
+				existing = node.Content[i]
+			}
+			content[i] = mergeNode(existing, item, order, joinPath(path, strconv.Itoa(i)))
+		}
+		node.Content = content
+		return node
+	}
+
+	if node != nil && node.Kind == yaml.ScalarNode {
+		var current any
+		if node.Decode(&current) == nil && fmt.Sprint(current) == fmt.Sprint(value) {
+			return node
+		}
+	}
+	updated := &yaml.Node{}
+	if err := updated.Encode(value); err != nil {
+		updated = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(value)}
+	}
+	if node != nil {
+		updated.HeadComment, updated.LineComment, updated.FootComment = node.HeadComment, node.LineComment, node.FootComment
+	}
+	return updated
+}
+
+// encodeNode writes a YAML document node as YAML or JSON, as picked by the
+// extension of path.
+func encodeNode(path string, root *yaml.Node) ([]byte, error) {
+	if codecFor(path) == codecYAML {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(root); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var compact bytes.Buffer
+	if len(root.Content) > 0 {
+		if err := writeJSON(&compact, root.Content[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		compact.WriteString("{}")
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
+
+// writeJSON writes node as compact JSON, keeping the order of its keys.
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	node = resolveAlias(node)
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i, pair := range mappingPairs(node) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(pair[0].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, pair[1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const convertConfig = `# Shared settings
+[defaults]
+user = "deploy"
+port = 2222
+
+[templates.web]
+commands = ["tail -f /var/log/nginx/access.log"]
+format = "combined"
+
+[[highlight]]
+name = "slow"
+pattern = 'took (\d+)ms'
+foreground = "#ffb86c"
+group = 1
+
+# Production web servers
+[[servers]]
+name = "web {{.Index}}"
+hosts = ["web[1-2].example.com"]
+extends = "web"
+tags = ["prod", "web"]
+password = "secret"
+
+[[servers]]
+name = "db"
+host = "db.example.com"
+port = 22
+private_key_path = "/keys/db"
+commands = ["journalctl -f", "uptime"]
+
+[structured]
+time_fields = ["ts"]
+`
+
+// loadServers loads the config at path and returns its servers and global
+// highlight rules without the files they came from.
+func loadServers(t *testing.T, path string) ([]SSHServer, []HighlightRule) {
+	t.Helper()
+	cfg, problems := decodeConfig(path)
+	for _, p := range problems {
+		if !p.Warning {
+			t.Fatalf("loading %s: %s", filepath.Base(path), p)
+		}
+	}
+	for i := range cfg.Servers {
+		cfg.Servers[i].File = ""
+	}
+	return cfg.Servers, cfg.Highlights
+}
+
+func TestConvertFile(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": convertConfig})
+	from := filepath.Join(dir, "servers.toml")
+	wantServers, wantHighlights := loadServers(t, from)
+
+	// Each conversion starts from the previous one.
+	for _, name := range []string{"servers.yaml", "servers.json", "converted.toml"} {
+		to := filepath.Join(dir, name)
+		if err := ConvertFile(from, to); err != nil {
+			t.Fatalf("converting to %s: %v", name, err)
+		}
+
+		servers, highlights := loadServers(t, to)
+		if !reflect.DeepEqual(servers, wantServers) {
+			t.Errorf("%s servers = %+v\nwant %+v", name, servers, wantServers)
+		}
+		if !reflect.DeepEqual(highlights, wantHighlights) {
+			t.Errorf("%s highlight rules = %+v\nwant %+v", name, highlights, wantHighlights)
+		}
+
+		data, err := os.ReadFile(to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Keys keep the order they had in the file.
+		text := string(data)
+		order := []string{"defaults", "templates", "highlight", "servers", "structured"}
+		for i := 1; i < len(order); i++ {
+			if strings.Index(text, order[i-1]) > strings.Index(text, order[i]) {
+				t.Errorf("%s: %s comes after %s:\n%s", name, order[i-1], order[i], text)
+			}
+		}
+		if strings.Index(text, "db.example.com") > strings.Index(text, "/keys/db") {
+			t.Errorf("%s: server keys were reordered:\n%s", name, text)
+		}
+
+		from = to
+	}
+}
+
+func TestConvertFileKeepsYAMLComments(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.yaml": `# Production
+servers:
+  # The database
+  - name: db
+    host: db.example.com # primary
+    password: secret
+`})
+
+	to := filepath.Join(dir, "copy.yaml")
+	if err := ConvertFile(filepath.Join(dir, "servers.yaml"), to); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comment := range []string{"# Production", "# The database", "# primary"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("comment %q was dropped:\n%s", comment, data)
+		}
+	}
+}
+
+func TestConvertFileProblems(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"servers.toml": convertConfig,
+		"servers.yaml": "servers: []\n",
+		"broken.json":  `{"servers": [`,
+	})
+
+	tests := []struct {
+		from, to string
+		err      string
+	}{
+		{"servers.toml", "servers.yaml", "already exists"},
+		{"missing.toml", "new.yaml", "failed to read config file"},
+		{"broken.json", "new.toml", "failed to parse config file"},
+	}
+	for _, tc := range tests {
+		err := ConvertFile(filepath.Join(dir, tc.from), filepath.Join(dir, tc.to))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("ConvertFile(%s, %s) = %v, want an error containing %q", tc.from, tc.to, err, tc.err)
+		}
+	}
+}
@@ -4,76 +4,308 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
-	"github.com/pelletier/go-toml/v2"
+	"github.com/toyz/ssh-thing/util"
 )
 
 type SSHServer struct {
-	Name           string   `toml:"name"`
-	Host           string   `toml:"host"`
-	User           string   `toml:"user"`
-	Port           int      `toml:"port"`
-	PrivateKeyPath string   `toml:"private_key_path"`
-	Password       string   `toml:"password"`
-	Commands       []string `toml:"commands"`
+	Name           string          `toml:"name"`
+	Host           string          `toml:"host"`
+	User           string          `toml:"user"`
+	Port           int             `toml:"port"`
+	PrivateKeyPath string          `toml:"private_key_path"`
+	Password       string          `toml:"password"`
+	Commands       []string        `toml:"commands"`
+	Group          string          `toml:"group,omitempty"`
+	Tags           []string        `toml:"tags,omitempty"`
+	Format         string          `toml:"format,omitempty"`
+	Highlights     []HighlightRule `toml:"highlight,omitempty"`
+
+	// PasswordCommand is run with the shell when connecting and the first
+	// line it prints is used as the password.
+	PasswordCommand string `toml:"password_command,omitempty"`
+
+	// PasswordFile is read when connecting and its first line is used as the
+	// password.
+	PasswordFile string `toml:"password_file,omitempty"`
+
+	// PrivateKeyPassphrase decrypts the private key when it is protected.
+	PrivateKeyPassphrase string `toml:"private_key_passphrase,omitempty"`
+
+	// Hosts expands the entry into one server per host. Hosts can contain
+	// numeric ranges and lists in brackets, e.g. web[01-24].example.com or
+	// db-[east,west].example.com.
+	Hosts []string `toml:"hosts,omitempty"`
+
+	// Extends names the template the server inherits from.
+	Extends string `toml:"extends,omitempty"`
+
+	// File is the config file the server was loaded from, and Table the
+	// index of its [[servers]] table in that file.
+	File  string `toml:"-"`
+	Table int    `toml:"-"`
+
+	// Generated is set for servers expanded from hosts, which share their
+	// table with the other hosts, and for servers from an inventory. Neither
+	// can be edited on their own.
+	Generated bool `toml:"-"`
+
+	// Source names the inventory the server came from, if any.
+	Source string `toml:"-"`
+
+	// Inherited maps each key that was filled in from [defaults] or a
+	// template to where it came from.
+	Inherited map[string]string `toml:"-"`
 }
 
 type Config struct {
-	Servers []SSHServer `toml:"servers"`
+	// Version is the version of the file format the file was written in;
+	// see ServerMigrations.
+	Version int `toml:"version,omitempty"`
+
+	// Schema is the JSON Schema editors use to check and complete the file.
+	Schema string `toml:"$schema,omitempty"`
+
+	Servers    []SSHServer          `toml:"servers"`
+	Defaults   SSHServer            `toml:"defaults,omitempty"`
+	Templates  map[string]SSHServer `toml:"templates,omitempty"`
+	Highlights []HighlightRule      `toml:"highlight,omitempty"`
+	Structured StructuredConfig     `toml:"structured,omitempty"`
+	Inventory  []InventorySource    `toml:"inventory,omitempty"`
+
+	// Include lists more config files to load, as paths or glob patterns
+	// relative to the file that includes them.
+	Include []string `toml:"include,omitempty"`
+
+	// Path is the file or directory the config was loaded from.
+	Path string `toml:"-"`
+
+	// Warnings lists the problems found that didn't stop the config from
+	// loading.
+	Warnings []Problem `toml:"-"`
+
+	// files are the files the config was merged from and watched the
+	// directories their includes were matched in.
+	files   []string
+	watched []string
+
+	// highlightOrigins holds where each of Highlights was defined, and
+	// structuredFile the file that set [structured].
+	highlightOrigins []origin
+	structuredFile   string
+
+	// failedInventory holds why each inventory that failed to load failed,
+	// by its label.
+	failedInventory map[string]error
 }
 
+// LoadConfig loads the servers file at filePath, or when it is empty the
+// first servers or config file found in the search directories.
 func LoadConfig(filePath string) (*Config, error) {
 	if filePath == "" {
-		filePath = "servers.toml"
+		path, ok := FindFile(serversFiles...)
+		if !ok {
+			return nil, fmt.Errorf("no servers or config file (%s) found in %s", strings.Join(Extensions, ", "), strings.Join(SearchDirs(), ", "))
+		}
+		filePath = path
+	}
 
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			if _, err := os.Stat("config.toml"); err == nil {
-				filePath = "config.toml"
-			}
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	cfg, problems := decodeConfig(filePath)
+	var errs []Problem
+	for _, problem := range problems {
+		if problem.Warning {
+			cfg.Warnings = append(cfg.Warnings, problem)
+		} else {
+			errs = append(errs, problem)
 		}
 	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return &cfg, nil
+}
 
-	data, err := os.ReadFile(filePath)
+// ApplyDefaults fills in the default port and expands a leading ~/ in the
+// private key path.
+func (s *SSHServer) ApplyDefaults() error {
+	if s.Port == 0 {
+		s.Port = 22
+	}
+
+	keyPath, err := expandHome(s.PrivateKeyPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+		return err
 	}
+	s.PrivateKeyPath = keyPath
 
-	var cfg Config
-	if err := toml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	return nil
+}
+
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
 	}
 
-	for i, server := range cfg.Servers {
-		if cfg.Servers[i].Port == 0 {
-			cfg.Servers[i].Port = 22
-		}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, path[2:]), nil
+}
 
-		if strings.HasPrefix(server.PrivateKeyPath, "~/") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get user home directory: %w", err)
-			}
-			cfg.Servers[i].PrivateKeyPath = filepath.Join(homeDir, server.PrivateKeyPath[2:])
-		}
+// EditConfigFile applies edit to the config file at filePath and writes it
+// back, leaving everything edit does not touch as it was. YAML and JSON
+// files are edited as the TOML they convert to.
+//
+// The file keeps its version. A file that is loaded through migrations that
+// change its settings is not edited, as the edit would mix the two formats;
+// it has to be upgraded with "ssh-thing config migrate" first.
+func EditConfigFile(filePath string, edit func(*Document) error) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file %s: %w", filePath, err)
 	}
 
-	return &cfg, nil
+	doc, err := parseFile(filePath, data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+	if len(data) == 0 {
+		doc.setVersion(ServerMigrations.Current())
+	} else if _, edited, err := ServerMigrations.migrate(ParseDocument(doc.Bytes())); err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	} else if edited {
+		return fmt.Errorf("%s is in an older format; run \"ssh-thing config migrate\" before editing it", filePath)
+	}
+	if err := edit(doc); err != nil {
+		return fmt.Errorf("failed to edit config file %s: %w", filePath, err)
+	}
+
+	data, err = encodeFile(filePath, data, doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode config file %s: %w", filePath, err)
+	}
+	return writeConfigFile(filePath, data)
 }
 
+// SaveConfig writes cfg to filePath. An existing file is edited in place so
+// its comments, formatting and ordering are kept; only the server settings
+// that differ from the file are rewritten.
 func SaveConfig(cfg *Config, filePath string) error {
 	if filePath == "" {
 		filePath = "servers.toml"
 	}
 
-	data, err := toml.Marshal(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		written := *cfg
+		written.Version = ServerMigrations.Current()
+		data, err := Marshal(filePath, &written)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return writeConfigFile(filePath, data)
+	}
+
+	current, docs, problems, ok := decodeFiles(filePath)
+	if !ok {
+		return fmt.Errorf("failed to parse config file %s", problems[0])
+	}
+	// Servers are compared as loaded, so values inherited from defaults or
+	// templates are not copied into each server's table.
+	current.load(docs, false)
+
+	if !reflect.DeepEqual(cfg.Highlights, current.Highlights) || !reflect.DeepEqual(cfg.Structured, current.Structured) ||
+		!reflect.DeepEqual(cfg.Defaults, current.Defaults) || !reflect.DeepEqual(cfg.Templates, current.Templates) {
+		return fmt.Errorf("failed to save config to %s: highlight, structured, defaults and template settings can only be changed by editing the file", filePath)
+	}
+
+	currentOwn, currentGenerated := splitGenerated(current.Servers)
+	updatedOwn, updatedGenerated := splitGenerated(withoutInventory(cfg.Servers))
+	if !reflect.DeepEqual(currentGenerated, updatedGenerated) {
+		return fmt.Errorf("failed to save config to %s: servers expanded from hosts can only be changed by editing the file", filePath)
+	}
+
+	// Each server is written to the file it came from, and new servers to
+	// the servers file.
+	byFile := make(map[string][]SSHServer)
+	for _, server := range updatedOwn {
+		file := server.File
+		if file == "" {
+			file = current.ServersFile()
+		}
+		if _, ok := docs[file]; !ok {
+			return fmt.Errorf("failed to save config to %s: server %s is from %s, which is no longer included", filePath, server.Name, file)
+		}
+		byFile[file] = append(byFile[file], server)
+	}
+
+	for _, file := range current.files {
+		var before []SSHServer
+		for _, server := range currentOwn {
+			if server.File == file {
+				before = append(before, server)
+			}
+		}
+		after := byFile[file]
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		if err := EditConfigFile(file, func(doc *Document) error {
+			return doc.updateServers(before, after)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeConfigFile replaces filePath atomically by writing to a temporary file
+// in the same directory and renaming it over the original. The file is only
+// readable by the owner while it contains passwords.
+func writeConfigFile(filePath string, data []byte) error {
+	var cfg Config
+	if err := unmarshalFile(filePath, data, &cfg); err != nil {
+		return fmt.Errorf("refusing to write invalid config to %s: %w", filePath, err)
 	}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	// Replace the file a symlink points to rather than the link itself.
+	if target, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = target
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if cfg.hasSecrets() {
+		perm &= 0600
+	}
+
+	if err := util.WriteFileAtomic(filePath, data, perm); err != nil {
 		return fmt.Errorf("failed to write config to %s: %w", filePath, err)
 	}
 
 	return nil
 }
+
+// hasSecrets reports whether any server, the defaults or a template sets a
+// password or a key passphrase.
+func (c *Config) hasSecrets() bool {
+	servers := append([]SSHServer{c.Defaults}, c.Servers...)
+	for _, template := range c.Templates {
+		servers = append(servers, template)
+	}
+	for _, server := range servers {
+		if server.Password != "" || server.PrivateKeyPassphrase != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,583 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Document is a TOML file kept as text, so single values can be changed
+// without disturbing comments, formatting or the order of the rest of the
+// file.
+type Document struct {
+	lines []string
+
+	// origins holds, for a document converted from YAML or JSON, the
+	// position in that file each line came from.
+	origins []position
+}
+
+type statementKind int
+
+const (
+	stmtOther statementKind = iota
+	stmtTable
+	stmtKeyValue
+)
+
+// statement is a table header or key/value pair, possibly spanning several
+// lines. For key/value pairs the value runs from (start, valueCol) to
+// (end, valueEnd).
+type statement struct {
+	kind  statementKind
+	name  string
+	array bool
+	start int
+	end   int
+
+	valueCol int
+	valueEnd int
+}
+
+// KeyValue is a key and the value to write for it.
+type KeyValue struct {
+	Key   string
+	Value any
+}
+
+func ParseDocument(data []byte) *Document {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return &Document{}
+	}
+	return &Document{lines: strings.Split(text, "\n")}
+}
+
+func (d *Document) Bytes() []byte {
+	if len(d.lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(d.lines, "\n") + "\n")
+}
+
+// statements splits the document into headers and key/value pairs. Lines
+// inside multi-line strings and arrays are part of the value they belong to,
+// so they are never mistaken for headers.
+func (d *Document) statements() []statement {
+	var stmts []statement
+
+	for i := 0; i < len(d.lines); i++ {
+		trimmed := strings.TrimSpace(d.lines[i])
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			stmts = append(stmts, statement{kind: stmtOther, start: i, end: i})
+
+		case strings.HasPrefix(trimmed, "["):
+			array := strings.HasPrefix(trimmed, "[[")
+			name := strings.TrimLeft(trimmed, "[")
+			if end := strings.Index(name, "]"); end >= 0 {
+				name = name[:end]
+			}
+			stmts = append(stmts, statement{kind: stmtTable, name: normalizeKey(name), array: array, start: i, end: i})
+
+		default:
+			eq := keyEnd(d.lines[i])
+			if eq < 0 {
+				stmts = append(stmts, statement{kind: stmtOther, start: i, end: i})
+				continue
+			}
+
+			stmt := statement{
+				kind:  stmtKeyValue,
+				name:  normalizeKey(d.lines[i][:eq]),
+				start: i,
+			}
+
+			col := eq + 1
+			for col < len(d.lines[i]) && (d.lines[i][col] == ' ' || d.lines[i][col] == '\t') {
+				col++
+			}
+			stmt.valueCol = col
+			stmt.end, stmt.valueEnd = d.valueEnd(i, col)
+			stmts = append(stmts, stmt)
+			i = stmt.end
+		}
+	}
+
+	return stmts
+}
+
+// keyEnd returns the index of the '=' separating a key from its value, or -1
+// if the line is not a key/value pair.
+func keyEnd(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		case c == '#':
+			return -1
+		}
+	}
+	return -1
+}
+
+func normalizeKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ".")
+}
+
+// valueEnd scans a value starting at (line, col) and returns the line and
+// column just past its last character, skipping strings, nested arrays and
+// inline tables and stopping at a comment.
+func (d *Document) valueEnd(line, col int) (int, int) {
+	depth := 0
+	var quote string
+	endLine, endCol := line, col
+
+	for l := line; l < len(d.lines); l++ {
+		text := d.lines[l]
+		c := 0
+		if l == line {
+			c = col
+		}
+
+		for c < len(text) {
+			if quote != "" {
+				if quote[0] == '"' && text[c] == '\\' {
+					c += 2
+					continue
+				}
+				if strings.HasPrefix(text[c:], quote) {
+					c += len(quote)
+					quote = ""
+					endLine, endCol = l, c
+					continue
+				}
+				c++
+				continue
+			}
+
+			switch ch := text[c]; {
+			case strings.HasPrefix(text[c:], `"""`) || strings.HasPrefix(text[c:], `'''`):
+				quote = text[c : c+3]
+				c += 3
+				continue
+			case ch == '"' || ch == '\'':
+				quote = string(ch)
+			case ch == '[' || ch == '{':
+				depth++
+			case ch == ']' || ch == '}':
+				depth--
+			case ch == '#':
+				c = len(text)
+				continue
+			}
+
+			if text[c] != ' ' && text[c] != '\t' {
+				endLine, endCol = l, c+1
+			}
+			c++
+		}
+
+		// Single-line strings end at the end of the line.
+		if len(quote) == 1 {
+			quote = ""
+		}
+		if depth <= 0 && quote == "" {
+			return endLine, endCol
+		}
+	}
+
+	return endLine, endCol
+}
+
+// serverBlock is the range of lines belonging to the index-th [[servers]]
+// table: its header, its keys and any [servers.*] sub-tables, plus the
+// position after its last key where new keys are inserted.
+type serverBlock struct {
+	start, end int
+	insertAt   int
+	keys       map[string]statement
+}
+
+func (d *Document) serverBlock(index int) (serverBlock, error) {
+	return d.arrayTableBlock("servers", index)
+}
+
+// arrayTableBlock is the range of lines belonging to the index-th [[name]]
+// table.
+func (d *Document) arrayTableBlock(name string, index int) (serverBlock, error) {
+	stmts := d.statements()
+	count := -1
+
+	for i, stmt := range stmts {
+		if stmt.kind != stmtTable || !stmt.array || stmt.name != name {
+			continue
+		}
+		count++
+		if count != index {
+			continue
+		}
+
+		block := serverBlock{start: stmt.start, end: len(d.lines) - 1, insertAt: stmt.end + 1, keys: make(map[string]statement)}
+		ownKeys := true
+		for _, next := range stmts[i+1:] {
+			if next.kind == stmtTable {
+				if !strings.HasPrefix(next.name, name+".") {
+					// Comments directly above the next header belong to it.
+					block.end = next.start - 1
+					for block.end > stmt.start && strings.HasPrefix(strings.TrimSpace(d.lines[block.end]), "#") {
+						block.end--
+					}
+					break
+				}
+				ownKeys = false
+			}
+			if ownKeys && next.kind == stmtKeyValue {
+				block.keys[next.name] = next
+				block.insertAt = next.end + 1
+			}
+		}
+		return block, nil
+	}
+
+	return serverBlock{}, fmt.Errorf("%s %d not found as a [[%s]] table", name, index, name)
+}
+
+// position returns the 1-based line and column of key in the index-th
+// [[table]], or of the table header when key is empty or not set. It returns
+// 0, 0 when the table does not exist.
+func (d *Document) position(table string, index int, key string) (int, int) {
+	block, err := d.arrayTableBlock(table, index)
+	if err != nil {
+		return 0, 0
+	}
+
+	if stmt, ok := block.keys[key]; ok {
+		return d.lineStart(stmt.start)
+	}
+	return d.lineStart(block.start)
+}
+
+// tablePosition returns the 1-based line and column of key in the [table]
+// table, or of its header when key is empty or not set. It returns 0, 0 when
+// the table does not exist.
+func (d *Document) tablePosition(table, key string) (int, int) {
+	stmts := d.statements()
+	for i, stmt := range stmts {
+		if stmt.kind != stmtTable || stmt.array || stmt.name != table {
+			continue
+		}
+
+		found := stmt
+		for _, next := range stmts[i+1:] {
+			if next.kind == stmtTable {
+				break
+			}
+			if next.kind == stmtKeyValue && next.name == key {
+				found = next
+				break
+			}
+		}
+
+		return d.lineStart(found.start)
+	}
+	return 0, 0
+}
+
+// ServerName returns the name set in the index-th [[servers]] table.
+func (d *Document) ServerName(index int) (string, error) {
+	block, err := d.serverBlock(index)
+	if err != nil {
+		return "", err
+	}
+
+	stmt, ok := block.keys["name"]
+	if !ok {
+		return "", nil
+	}
+
+	value := strings.Join(d.lines[stmt.start:stmt.end+1], "\n")
+	value = value[stmt.valueCol:]
+	value = value[:len(value)-(len(d.lines[stmt.end])-stmt.valueEnd)]
+
+	var decoded struct {
+		V string `toml:"v"`
+	}
+	if err := toml.Unmarshal([]byte("v = "+value), &decoded); err != nil {
+		return "", fmt.Errorf("failed to read the name of server %d: %w", index, err)
+	}
+	return decoded.V, nil
+}
+
+// ServerCount returns the number of [[servers]] tables in the document.
+func (d *Document) ServerCount() int {
+	count := 0
+	for _, stmt := range d.statements() {
+		if stmt.kind == stmtTable && stmt.array && stmt.name == "servers" {
+			count++
+		}
+	}
+	return count
+}
+
+// SetServerValue sets a key of the index-th server, replacing the existing
+// value in place or adding the key after the server's last key. A nil value
+// removes the key.
+func (d *Document) SetServerValue(index int, key string, value any) error {
+	block, err := d.serverBlock(index)
+	if err != nil {
+		return err
+	}
+
+	stmt, exists := block.keys[key]
+	if value == nil {
+		if exists {
+			d.splice(stmt.start, stmt.end+1, nil)
+		}
+		return nil
+	}
+
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+
+	if !exists {
+		d.splice(block.insertAt, block.insertAt, []string{key + " = " + encoded})
+		return nil
+	}
+
+	first := d.lines[stmt.start][:stmt.valueCol]
+	rest := d.lines[stmt.end][stmt.valueEnd:]
+	d.splice(stmt.start, stmt.end+1, strings.Split(first+encoded+rest, "\n"))
+	return nil
+}
+
+// AppendServer adds a [[servers]] table with the given keys to the end of the
+// document.
+func (d *Document) AppendServer(values []KeyValue) error {
+	lines := []string{"[[servers]]"}
+	for _, kv := range values {
+		if kv.Value == nil {
+			continue
+		}
+		encoded, err := encodeValue(kv.Value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", kv.Key, err)
+		}
+		lines = append(lines, kv.Key+" = "+encoded)
+	}
+
+	if len(d.lines) > 0 && strings.TrimSpace(d.lines[len(d.lines)-1]) != "" {
+		lines = append([]string{""}, lines...)
+	}
+	d.splice(len(d.lines), len(d.lines), lines)
+	return nil
+}
+
+// RemoveServer deletes the index-th [[servers]] table along with the comment
+// lines directly above it, unless they open the file.
+func (d *Document) RemoveServer(index int) error {
+	block, err := d.serverBlock(index)
+	if err != nil {
+		return err
+	}
+
+	start := block.start
+	for start > 0 && strings.HasPrefix(strings.TrimSpace(d.lines[start-1]), "#") {
+		start--
+	}
+	// Comments at the top of the file describe the file, not the server.
+	var keep []string
+	if start == 0 && start != block.start {
+		start = block.start
+		keep = []string{""}
+	}
+
+	d.splice(start, block.end+1, keep)
+	for len(d.lines) > 0 && strings.TrimSpace(d.lines[len(d.lines)-1]) == "" {
+		d.lines = d.lines[:len(d.lines)-1]
+	}
+	return nil
+}
+
+func (d *Document) splice(start, end int, lines []string) {
+	result := make([]string, 0, len(d.lines)-(end-start)+len(lines))
+	result = append(result, d.lines[:start]...)
+	result = append(result, lines...)
+	result = append(result, d.lines[end:]...)
+	d.lines = result
+
+	if d.origins != nil {
+		// New lines are reported at the position of the line they replace.
+		var at position
+		if start < len(d.origins) {
+			at = d.origins[start]
+		} else if start > 0 {
+			at = d.origins[start-1]
+		}
+		origins := make([]position, 0, len(d.lines))
+		origins = append(origins, d.origins[:start]...)
+		for range lines {
+			origins = append(origins, at)
+		}
+		d.origins = append(origins, d.origins[end:]...)
+	}
+}
+
+// encodeValue formats a value as TOML, using double-quoted strings to match
+// the style of hand-written config files.
+func encodeValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return quoteString(v), nil
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = quoteString(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]", nil
+	case []any:
+		// Lists decoded from YAML or JSON are written like []string when
+		// they only hold strings.
+		strs := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				strs = nil
+				break
+			}
+			strs[i] = s
+		}
+		if strs != nil {
+			return encodeValue(strs)
+		}
+	}
+
+	data, err := toml.Marshal(map[string]any{"v": value})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(data), "v = "), "\n"), nil
+}
+
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// rootPosition returns the 1-based line and column of a key set before the
+// first table, or 0, 0 when it is not set.
+func (d *Document) rootPosition(key string) (int, int) {
+	for _, stmt := range d.statements() {
+		if stmt.kind == stmtTable {
+			break
+		}
+		if stmt.kind == stmtKeyValue && stmt.name == key {
+			return d.lineStart(stmt.start)
+		}
+	}
+	return 0, 0
+}
+
+// hasTable reports whether the document has a [name] table or any table
+// nested under it.
+func (d *Document) hasTable(name string) bool {
+	for _, stmt := range d.statements() {
+		if stmt.kind == stmtTable && (stmt.name == name || strings.HasPrefix(stmt.name, name+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineStart returns the 1-based line and column of the first character of
+// the line at index, in the file the document was parsed from.
+func (d *Document) lineStart(index int) (int, int) {
+	if d.origins != nil {
+		return d.origins[index].line, d.origins[index].column
+	}
+	line := d.lines[index]
+	return index + 1, len(line) - len(strings.TrimLeft(line, " \t")) + 1
+}
+
+// sourcePosition maps a 1-based position in the document, such as one
+// reported when decoding it, to the file the document was parsed from.
+func (d *Document) sourcePosition(line, column int) (int, int) {
+	if d.origins == nil || line < 1 || line > len(d.origins) {
+		return line, column
+	}
+	return d.origins[line-1].line, d.origins[line-1].column
+}
+
+// setVersion sets the version key, which is added at the top of the
+// document below any comment block that heads the file.
+func (d *Document) setVersion(version int) {
+	for _, stmt := range d.statements() {
+		if stmt.kind == stmtTable {
+			break
+		}
+		if stmt.kind == stmtKeyValue && stmt.name == "version" {
+			first := d.lines[stmt.start][:stmt.valueCol]
+			rest := d.lines[stmt.end][stmt.valueEnd:]
+			d.splice(stmt.start, stmt.end+1, []string{first + fmt.Sprint(version) + rest})
+			return
+		}
+	}
+
+	at := 0
+	for at < len(d.lines) && strings.HasPrefix(strings.TrimSpace(d.lines[at]), "#") {
+		at++
+	}
+	if at == len(d.lines) || strings.TrimSpace(d.lines[at]) != "" {
+		at = 0
+	} else {
+		at++
+	}
+
+	lines := []string{fmt.Sprintf("version = %d", version)}
+	if at < len(d.lines) && strings.TrimSpace(d.lines[at]) != "" {
+		lines = append(lines, "")
+	}
+	d.splice(at, at, lines)
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const documentInput = `# Servers for the team
+version = 1
+
+# The web server
+[[servers]]
+name = "web"
+host = "web.example.com" # primary
+commands = [
+  "tail -f /var/log/nginx/access.log",
+  "uptime",
+]
+
+[[servers.highlight]]
+pattern = "5\\d\\d"
+
+# The database
+[[servers]]
+name = "db"
+host = "db.example.com"
+`
+
+func TestDocumentEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(d *Document) error
+		want string
+	}{
+		{
+			name: "replace a value",
+			edit: func(d *Document) error { return d.SetServerValue(0, "host", "web2.example.com") },
+			want: strings.Replace(documentInput, `host = "web.example.com" # primary`, `host = "web2.example.com" # primary`, 1),
+		},
+		{
+			name: "replace a multi-line value",
+			edit: func(d *Document) error { return d.SetServerValue(0, "commands", []string{"uptime"}) },
+			want: strings.Replace(documentInput, `commands = [
+  "tail -f /var/log/nginx/access.log",
+  "uptime",
+]`, `commands = ["uptime"]`, 1),
+		},
+		{
+			name: "add a key after the server's own keys",
+			edit: func(d *Document) error { return d.SetServerValue(0, "port", 2222) },
+			want: strings.Replace(documentInput, "  \"uptime\",\n]\n", "  \"uptime\",\n]\nport = 2222\n", 1),
+		},
+		{
+			name: "add a key to the last server",
+			edit: func(d *Document) error { return d.SetServerValue(1, "tags", []string{"prod", "db"}) },
+			want: documentInput + `tags = ["prod", "db"]` + "\n",
+		},
+		{
+			name: "remove a key",
+			edit: func(d *Document) error { return d.SetServerValue(0, "host", nil) },
+			want: strings.Replace(documentInput, "host = \"web.example.com\" # primary\n", "", 1),
+		},
+		{
+			name: "quote strings",
+			edit: func(d *Document) error { return d.SetServerValue(1, "password", `a "quoted" \ value`) },
+			want: documentInput + `password = "a \"quoted\" \\ value"` + "\n",
+		},
+		{
+			name: "append a server",
+			edit: func(d *Document) error {
+				return d.AppendServer([]KeyValue{{"name", "cache"}, {"host", "cache.example.com"}, {"user", nil}, {"port", 6380}})
+			},
+			want: documentInput + `
+[[servers]]
+name = "cache"
+host = "cache.example.com"
+port = 6380
+`,
+		},
+		{
+			name: "remove a server with its comment",
+			edit: func(d *Document) error { return d.RemoveServer(0) },
+			want: `# Servers for the team
+version = 1
+
+# The database
+[[servers]]
+name = "db"
+host = "db.example.com"
+`,
+		},
+		{
+			name: "remove the last server",
+			edit: func(d *Document) error { return d.RemoveServer(1) },
+			want: strings.TrimSuffix(documentInput, `
+# The database
+[[servers]]
+name = "db"
+host = "db.example.com"
+`),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := ParseDocument([]byte(documentInput))
+			if err := tc.edit(doc); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(doc.Bytes()); got != tc.want {
+				t.Errorf("document:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDocumentRemoveFirstServerKeepsFileComment(t *testing.T) {
+	doc := ParseDocument([]byte(`# Servers for the team
+[[servers]]
+name = "web"
+host = "web.example.com"
+
+[[servers]]
+name = "db"
+host = "db.example.com"
+`))
+	if err := doc.RemoveServer(0); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Servers for the team
+
+[[servers]]
+name = "db"
+host = "db.example.com"
+`
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("document:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDocumentServers(t *testing.T) {
+	doc := ParseDocument([]byte(documentInput))
+	if n := doc.ServerCount(); n != 2 {
+		t.Errorf("ServerCount = %d, want 2", n)
+	}
+	for i, want := range []string{"web", "db"} {
+		if name, err := doc.ServerName(i); err != nil || name != want {
+			t.Errorf("ServerName(%d) = %q, %v, want %q", i, name, err, want)
+		}
+	}
+
+	for _, edit := range []func() error{
+		func() error { return doc.SetServerValue(2, "host", "x") },
+		func() error { return doc.RemoveServer(5) },
+		func() error { _, err := doc.ServerName(2); return err },
+	} {
+		if err := edit(); err == nil {
+			t.Error("editing a server that does not exist succeeded")
+		}
+	}
+	if got := string(doc.Bytes()); got != documentInput {
+		t.Errorf("failed edits changed the document:\n%s", got)
+	}
+}
+
+func TestUpdateServerWritesChangedKeys(t *testing.T) {
+	doc := ParseDocument([]byte(documentInput))
+	old := SSHServer{Name: "web", Host: "web.example.com", Commands: []string{"tail -f /var/log/nginx/access.log", "uptime"}}
+	updated := old
+	updated.User = "deploy"
+
+	if err := doc.UpdateServer(0, old, updated); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(documentInput, "  \"uptime\",\n]\n", "  \"uptime\",\n]\nuser = \"deploy\"\n", 1)
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("document:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateServerWritesDefaultPort(t *testing.T) {
+	doc := ParseDocument([]byte("[[servers]]\nname = \"web\"\nextends = \"web\"\nport = 2222\n"))
+	old := SSHServer{Name: "web", Extends: "web", Port: 2222}
+	updated := old
+	updated.Port = 22
+
+	// The template may set another port, so 22 has to be written out.
+	if err := doc.UpdateServer(0, old, updated); err != nil {
+		t.Fatal(err)
+	}
+	want := "[[servers]]\nname = \"web\"\nextends = \"web\"\nport = 22\n"
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("document:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// serverValues lists the keys of a server that can be written in place, in
+// the order they are written for new servers. Unset values map to nil so the
+// key is omitted. A port of 22 is written like any other, as leaving it out
+// would let a template or [defaults] set a different one.
+func serverValues(s SSHServer) []KeyValue {
+	values := []KeyValue{
+		{Key: "name", Value: s.Name},
+		{Key: "extends", Value: s.Extends},
+		{Key: "host", Value: s.Host},
+		{Key: "user", Value: s.User},
+		{Key: "port", Value: s.Port},
+		{Key: "private_key_path", Value: s.PrivateKeyPath},
+		{Key: "private_key_passphrase", Value: s.PrivateKeyPassphrase},
+		{Key: "password", Value: s.Password},
+		{Key: "password_command", Value: s.PasswordCommand},
+		{Key: "password_file", Value: s.PasswordFile},
+		{Key: "commands", Value: s.Commands},
+		{Key: "group", Value: s.Group},
+		{Key: "tags", Value: s.Tags},
+		{Key: "format", Value: s.Format},
+	}
+
+	for i, kv := range values {
+		switch v := kv.Value.(type) {
+		case string:
+			if v == "" && kv.Key != "name" && kv.Key != "host" {
+				values[i].Value = nil
+			}
+		case int:
+			if v == 0 {
+				values[i].Value = nil
+			}
+		case []string:
+			if len(v) == 0 {
+				values[i].Value = nil
+			}
+		}
+	}
+	return values
+}
+
+// AddServer appends a [[servers]] table for server.
+func (d *Document) AddServer(server SSHServer) error {
+	return d.AppendServer(serverValues(server))
+}
+
+// UpdateServer writes the fields of the index-th server that differ between
+// old and updated, keeping the rest of its table untouched.
+func (d *Document) UpdateServer(index int, old, updated SSHServer) error {
+	before := serverValues(old)
+	for i, kv := range serverValues(updated) {
+		if equalValues(before[i].Value, kv.Value) {
+			continue
+		}
+		if err := d.SetServerValue(index, kv.Key, kv.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateServers rewrites the servers of the document, currently holding
+// current, to match updated. Servers are matched by name, and the remaining
+// ones in order, so a renamed server keeps its table. Matched servers are
+// edited in place, unmatched current servers are removed and unmatched
+// updated servers are appended. Servers expanded from hosts must not be
+// passed in either list.
+func (d *Document) updateServers(current, updated []SSHServer) error {
+	matched := make([]int, len(updated))
+	used := make([]bool, len(current))
+	for i := range updated {
+		matched[i] = -1
+		for j := range current {
+			if !used[j] && current[j].Name == updated[i].Name {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	next := 0
+	for i := range updated {
+		if matched[i] >= 0 {
+			continue
+		}
+		for next < len(current) && used[next] {
+			next++
+		}
+		if next < len(current) {
+			matched[i], used[next] = next, true
+		}
+	}
+
+	for i, j := range matched {
+		if j < 0 {
+			continue
+		}
+		if !reflect.DeepEqual(current[j].Highlights, updated[i].Highlights) {
+			return fmt.Errorf("server %s: highlight rules can only be changed by editing the file", updated[i].Name)
+		}
+		if err := d.UpdateServer(current[j].Table, current[j], updated[i]); err != nil {
+			return err
+		}
+	}
+
+	for j := len(current) - 1; j >= 0; j-- {
+		if used[j] {
+			continue
+		}
+		if err := d.RemoveServer(current[j].Table); err != nil {
+			return err
+		}
+	}
+
+	for i, j := range matched {
+		if j >= 0 {
+			continue
+		}
+		if len(updated[i].Highlights) > 0 {
+			return fmt.Errorf("server %s: highlight rules can only be added by editing the file", updated[i].Name)
+		}
+		if err := d.AddServer(updated[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutInventory drops the servers that came from an inventory, which are
+// never written to the servers file.
+func withoutInventory(servers []SSHServer) []SSHServer {
+	var own []SSHServer
+	for _, server := range servers {
+		if server.Source == "" {
+			own = append(own, server)
+		}
+	}
+	return own
+}
+
+// splitGenerated separates the servers with a table of their own from those
+// expanded from hosts.
+func splitGenerated(servers []SSHServer) (own, generated []SSHServer) {
+	for _, server := range servers {
+		if server.Generated {
+			generated = append(generated, server)
+		} else {
+			own = append(own, server)
+		}
+	}
+	return own, generated
+}
+
+func equalValues(a, b any) bool {
+	as, aok := a.([]string)
+	bs, bok := b.([]string)
+	if aok || bok {
+		if !aok || !bok || len(as) != len(bs) {
+			return false
+		}
+		for i := range as {
+			if as[i] != bs[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"github.com/toyz/ssh-thing/logs"
+)
+
+// HighlightRule colors every match of Pattern. When Group is set only that
+// capture group is colored instead of the whole match. Rules are applied in
+// order and earlier rules win where matches overlap.
+type HighlightRule struct {
+	Name       string `toml:"name"`
+	Pattern    string `toml:"pattern"`
+	Foreground string `toml:"foreground"`
+	Background string `toml:"background"`
+	Bold       bool   `toml:"bold"`
+	Underline  bool   `toml:"underline"`
+	Group      int    `toml:"group"`
+	Disabled   bool   `toml:"disabled"`
+}
+
+func DefaultHighlightRules() []HighlightRule {
+	return []HighlightRule{
+		{Name: "fatal", Pattern: logs.FatalPattern.String(), Foreground: "#FF0000", Bold: true},
+		{Name: "error", Pattern: logs.ErrorPattern.String(), Foreground: "#FF5555"},
+		{Name: "warn", Pattern: logs.WarnPattern.String(), Foreground: "#FFFF00"},
+		{Name: "info", Pattern: logs.InfoPattern.String(), Foreground: "#00AFFF"},
+		{Name: "debug", Pattern: logs.DebugPattern.String(), Foreground: "#8BE9FD"},
+		{Name: "trace", Pattern: logs.TracePattern.String(), Foreground: "#BD93F9"},
+		{Name: "timestamp", Pattern: logs.TimestampPattern.String(), Foreground: "#50FA7B"},
+	}
+}
+
+// HighlightRules returns the effective rules for a server: the built-in
+// defaults, then the global [[highlight]] rules, then the server's own
+// [[servers.highlight]] rules. A later rule with the same name as an earlier
+// one replaces it in place, and disabled rules are dropped.
+func (c *Config) HighlightRules(server *SSHServer) []HighlightRule {
+	rules := DefaultHighlightRules()
+	rules = mergeHighlightRules(rules, c.Highlights)
+	if server != nil {
+		rules = mergeHighlightRules(rules, server.Highlights)
+	}
+
+	effective := make([]HighlightRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.Disabled {
+			effective = append(effective, rule)
+		}
+	}
+	return effective
+}
+
+func mergeHighlightRules(base []HighlightRule, overrides []HighlightRule) []HighlightRule {
+	for _, rule := range overrides {
+		replaced := false
+		if rule.Name != "" {
+			for i := range base {
+				if base[i].Name == rule.Name {
+					base[i] = rule
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			base = append(base, rule)
+		}
+	}
+	return base
+}
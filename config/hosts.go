@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// maxExpandedHosts limits how many hosts a single entry can expand to, so a
+// typo in a range can't create millions of tabs.
+const maxExpandedHosts = 1000
+
+// hostName is the data available to the name template of an entry with
+// hosts.
+type hostName struct {
+	// Index is the 1-based position of the host in the expanded list.
+	Index int
+	// Host is the expanded host name.
+	Host string
+}
+
+// expandHosts replaces every server that sets hosts with one server per
+// host. The name is a text/template executed with a hostName; a name without
+// a template gets " {{.Index}}" appended so every server stays unique.
+func (c *Config) expandHosts(docs documents) []Problem {
+	var problems []Problem
+	report := func(server SSHServer, key string, format string, args ...any) {
+		problems = append(problems, docs.serverProblem(server, key, fmt.Sprintf(format, args...)))
+	}
+
+	var servers []SSHServer
+	for _, server := range c.Servers {
+		if len(server.Hosts) == 0 {
+			servers = append(servers, server)
+			continue
+		}
+
+		if server.Host != "" {
+			report(server, "host", "server %s: set either host or hosts, not both", server.Name)
+			continue
+		}
+
+		var hosts []string
+		for _, pattern := range server.Hosts {
+			expanded, err := expandHostPattern(pattern)
+			if err != nil {
+				report(server, "hosts", "server %s: %v", server.Name, err)
+				hosts = nil
+				break
+			}
+			hosts = append(hosts, expanded...)
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		if len(hosts) > maxExpandedHosts {
+			report(server, "hosts", "server %s: hosts expand to %d servers, more than the limit of %d", server.Name, len(hosts), maxExpandedHosts)
+			continue
+		}
+
+		name := server.Name
+		if !strings.Contains(name, "{{") {
+			name += " {{.Index}}"
+		}
+		tmpl, err := template.New("name").Option("missingkey=error").Parse(name)
+		if err != nil {
+			report(server, "name", "server %s: invalid name template: %v", server.Name, err)
+			continue
+		}
+
+		for i, host := range hosts {
+			var b strings.Builder
+			if err := tmpl.Execute(&b, hostName{Index: i + 1, Host: host}); err != nil {
+				report(server, "name", "server %s: invalid name template: %v", server.Name, err)
+				break
+			}
+
+			expanded := server
+			expanded.Name = b.String()
+			expanded.Host = host
+			expanded.Hosts = nil
+			expanded.Generated = true
+			servers = append(servers, expanded)
+		}
+	}
+
+	c.Servers = servers
+	return problems
+}
+
+// expandHostPattern expands the bracketed parts of a host pattern. A bracket
+// holds either a numeric range such as [01-24], keeping the zero padding of
+// its first number, or a comma-separated list such as [east,west]. Several
+// brackets expand to every combination, in order. A bracket that holds a
+// colon is an IPv6 literal such as [fe80::1] and is kept as it is.
+func expandHostPattern(pattern string) ([]string, error) {
+	start := strings.IndexByte(pattern, '[')
+	if start < 0 {
+		if strings.IndexByte(pattern, ']') >= 0 {
+			return nil, fmt.Errorf("host pattern %q has a ] without a [", pattern)
+		}
+		return []string{pattern}, nil
+	}
+
+	end := strings.IndexByte(pattern[start:], ']')
+	if end < 0 {
+		return nil, fmt.Errorf("host pattern %q has a [ without a ]", pattern)
+	}
+	end += start
+
+	values := []string{pattern[start : end+1]}
+	if inner := pattern[start+1 : end]; !strings.Contains(inner, ":") {
+		var err error
+		if values, err = expandBracket(inner); err != nil {
+			return nil, fmt.Errorf("host pattern %q: %w", pattern, err)
+		}
+	}
+
+	rest, err := expandHostPattern(pattern[end+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values)*len(rest) > maxExpandedHosts {
+		return nil, fmt.Errorf("host pattern %q expands to more than %d hosts", pattern, maxExpandedHosts)
+	}
+
+	hosts := make([]string, 0, len(values)*len(rest))
+	for _, value := range values {
+		for _, suffix := range rest {
+			hosts = append(hosts, pattern[:start]+value+suffix)
+		}
+	}
+	return hosts, nil
+}
+
+func expandBracket(inner string) ([]string, error) {
+	if from, to, ok := strings.Cut(inner, "-"); ok && isDigits(from) && isDigits(to) {
+		first, _ := strconv.Atoi(from)
+		last, _ := strconv.Atoi(to)
+		if first > last {
+			return nil, fmt.Errorf("range [%s] runs backwards", inner)
+		}
+		if last-first >= maxExpandedHosts {
+			return nil, fmt.Errorf("range [%s] has more than %d values", inner, maxExpandedHosts)
+		}
+
+		width := 0
+		if len(from) > 1 && from[0] == '0' {
+			width = len(from)
+		}
+
+		values := make([]string, 0, last-first+1)
+		for n := first; n <= last; n++ {
+			values = append(values, fmt.Sprintf("%0*d", width, n))
+		}
+		return values, nil
+	}
+
+	var values []string
+	for _, value := range strings.Split(inner, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			return nil, fmt.Errorf("[%s] has an empty entry", inner)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
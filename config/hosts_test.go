@@ -0,0 +1,123 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandHostPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+		err     string
+	}{
+		{pattern: "web.example.com", want: []string{"web.example.com"}},
+		{pattern: "web[1-3]", want: []string{"web1", "web2", "web3"}},
+		{pattern: "web[01-03].prod", want: []string{"web01.prod", "web02.prod", "web03.prod"}},
+		{pattern: "web[8-10]", want: []string{"web8", "web9", "web10"}},
+		{pattern: "web[5-5]", want: []string{"web5"}},
+		{pattern: "canary-[east,west]", want: []string{"canary-east", "canary-west"}},
+		{pattern: "[a, b ]-[1-2]", want: []string{"a-1", "a-2", "b-1", "b-2"}},
+		{pattern: "[web]", want: []string{"web"}},
+		{pattern: "[::1]", want: []string{"[::1]"}},
+		{pattern: "[fe80::1%eth0]", want: []string{"[fe80::1%eth0]"}},
+		{pattern: "[2001:db8::1]-[a,b]", want: []string{"[2001:db8::1]-a", "[2001:db8::1]-b"}},
+		{pattern: "web[3-1]", err: "range [3-1] runs backwards"},
+		{pattern: "web[1-5000]", err: "range [1-5000] has more than 1000 values"},
+		{pattern: "[1-100][1-100]", err: "expands to more than 1000 hosts"},
+		{pattern: "web[a,,b]", err: "[a,,b] has an empty entry"},
+		{pattern: "web[]", err: "[] has an empty entry"},
+		{pattern: "web[1-3", err: "has a [ without a ]"},
+		{pattern: "web1-3]", err: "has a ] without a ["},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.pattern, func(t *testing.T) {
+			got, err := expandHostPattern(tc.pattern)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expandHostPattern(%q) = %v, %v, want an error containing %q", tc.pattern, got, err, tc.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expandHostPattern(%q) = %q, %v, want %q", tc.pattern, got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpandHosts(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": `[[servers]]
+name = "web"
+hosts = ["web[1-2]", "canary"]
+password = "secret"
+
+[[servers]]
+name = "{{.Host}} (#{{.Index}})"
+hosts = ["db-[east,west]"]
+password = "secret"
+
+[[servers]]
+name = "single"
+host = "single.example.com"
+password = "secret"
+`})
+
+	cfg, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, nil)
+
+	var got []string
+	for _, server := range cfg.Servers {
+		got = append(got, server.Name+"="+server.Host)
+		if server.Generated != (server.Name != "single") {
+			t.Errorf("server %s: generated = %v", server.Name, server.Generated)
+		}
+	}
+	want := []string{
+		"web 1=web1",
+		"web 2=web2",
+		"web 3=canary",
+		"db-east (#1)=db-east",
+		"db-west (#2)=db-west",
+		"single=single.example.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("servers = %q\nwant %q", got, want)
+	}
+}
+
+func TestExpandHostsProblems(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": `[[servers]]
+name = "both"
+host = "web.example.com"
+hosts = ["web[1-2]"]
+password = "secret"
+
+[[servers]]
+name = "backwards"
+hosts = ["web[2-1]"]
+password = "secret"
+
+[[servers]]
+name = "{{.Missing}}"
+hosts = ["web[1-2]"]
+password = "secret"
+
+[[servers]]
+name = "too many"
+hosts = ["a[1-600]", "b[1-600]"]
+password = "secret"
+`})
+
+	_, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, []string{
+		"servers.toml:3:1: server both: set either host or hosts, not both",
+		`servers.toml:9:1: server backwards: host pattern "web[2-1]": range [2-1] runs backwards`,
+		`servers.toml:13:1: server {{.Missing}}: invalid name template: template: name:1:2: executing "name" at <.Missing>: can't evaluate field Missing in type config.hostName`,
+		"servers.toml:19:1: server too many: hosts expand to 1200 servers, more than the limit of 1000",
+		"servers.toml: no servers configured",
+	})
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// origin is where a table was defined: its file and its index among the
+// tables of the same name in that file.
+type origin struct {
+	file  string
+	index int
+}
+
+// documents are the parsed files of a config by path, used to find where a
+// problem is.
+type documents map[string]*Document
+
+// problem reports a problem at key in the index-th [[table]] of file.
+func (d documents) problem(file, table string, index int, key, message string) Problem {
+	p := Problem{File: file, Message: message}
+	if doc := d[file]; doc != nil {
+		p.Line, p.Column = doc.position(table, index, key)
+	}
+	return p
+}
+
+// tableProblem reports a problem at key in the [table] table of file.
+func (d documents) tableProblem(file, table, key, message string) Problem {
+	p := Problem{File: file, Message: message}
+	if doc := d[file]; doc != nil {
+		p.Line, p.Column = doc.tablePosition(table, key)
+	}
+	return p
+}
+
+// decodeFiles decodes the config at path, which is either a file or a
+// directory whose config files are loaded in name order, together with every
+// file they include. Each file's servers come before those of the files it
+// includes. ok is false when a file could not be parsed at all.
+func decodeFiles(path string) (cfg Config, docs documents, problems []Problem, ok bool) {
+	cfg.Path = path
+	docs = make(documents)
+
+	roots := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		roots = nil
+		for _, ext := range Extensions {
+			matches, _ := filepath.Glob(filepath.Join(path, "*"+ext))
+			roots = append(roots, matches...)
+		}
+		sort.Strings(roots)
+		cfg.watched = append(cfg.watched, path)
+		if len(roots) == 0 {
+			return cfg, docs, []Problem{{File: path, Message: "no config files in the directory"}}, false
+		}
+	}
+
+	ok = true
+	for _, root := range roots {
+		fileProblems, fileOK := cfg.include(root, docs)
+		problems = append(problems, fileProblems...)
+		ok = ok && fileOK
+	}
+	return cfg, docs, problems, ok
+}
+
+// include decodes file into c, followed by the files it includes. A file
+// that was already loaded, for example because two patterns match it, is
+// skipped, which also stops files from including each other forever.
+func (c *Config) include(file string, docs documents) ([]Problem, bool) {
+	if _, loaded := docs[file]; loaded {
+		return nil, true
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		docs[file] = nil
+		return []Problem{{File: file, Message: fmt.Sprintf("failed to read config file: %v", errors.Unwrap(err))}}, false
+	}
+	doc, err := parseFile(file, data)
+	if err != nil {
+		docs[file] = nil
+		problem := Problem{File: file, Message: err.Error()}
+		var syntax *syntaxError
+		if errors.As(err, &syntax) {
+			problem.Line, problem.Message = syntax.line, syntax.message
+		}
+		return []Problem{problem}, false
+	}
+	docs[file] = doc
+
+	_, migrated, err := ServerMigrations.migrate(doc)
+	if err != nil {
+		line, col := doc.rootPosition("version")
+		return []Problem{{File: file, Line: line, Column: col, Message: err.Error()}}, false
+	}
+	if migrated {
+		data = doc.Bytes()
+	}
+
+	part, problems, ok := decodeFile(file, data, doc)
+	if !ok {
+		return problems, false
+	}
+	c.files = append(c.files, file)
+	problems = append(problems, c.merge(part, file, docs)...)
+
+	for _, pattern := range part.Include {
+		files, err := c.includedFiles(file, pattern)
+		if err != nil {
+			line, col := doc.rootPosition("include")
+			problems = append(problems, Problem{File: file, Line: line, Column: col, Message: err.Error()})
+			continue
+		}
+		for _, included := range files {
+			includedProblems, includedOK := c.include(included, docs)
+			problems = append(problems, includedProblems...)
+			ok = ok && includedOK
+		}
+	}
+	return problems, ok
+}
+
+// includedFiles returns the files matched by an include pattern, relative
+// to the directory of the file including them, in name order. The directory
+// of a glob is watched so files added to it later are picked up on reload.
+func (c *Config) includedFiles(from, pattern string) ([]string, error) {
+	path, err := expandHome(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+
+	if !strings.ContainsAny(path, "*?[") {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("include %s: %v", pattern, errors.Unwrap(err))
+		}
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", pattern, err)
+	}
+	sort.Strings(files)
+	c.watched = append(c.watched, filepath.Dir(path))
+	return files, nil
+}
+
+// decodeFile parses one config file strictly, so unknown keys are reported
+// rather than ignored. ok is false for syntax errors, which stop decoding.
+// YAML and JSON files are decoded from the TOML doc was converted to.
+func decodeFile(file string, data []byte, doc *Document) (cfg Config, problems []Problem, ok bool) {
+	if codecFor(file) != codecTOML {
+		data = doc.Bytes()
+	}
+	decoder := toml.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&cfg)
+
+	var strict *toml.StrictMissingError
+	var decodeErr *toml.DecodeError
+	switch {
+	case errors.As(err, &strict):
+		for _, e := range strict.Errors {
+			line, col := doc.sourcePosition(e.Position())
+			problems = append(problems, Problem{
+				File:    file,
+				Line:    line,
+				Column:  col,
+				Message: "unknown key " + strings.Join(e.Key(), "."),
+			})
+		}
+	case errors.As(err, &decodeErr):
+		line, col := doc.sourcePosition(decodeErr.Position())
+		message := strings.TrimPrefix(decodeErr.Error(), "toml: ")
+		return cfg, []Problem{{File: file, Line: line, Column: col, Message: message}}, false
+	case err != nil:
+		return cfg, []Problem{{File: file, Message: err.Error()}}, false
+	}
+	return cfg, problems, true
+}
+
+// merge adds the tables decoded from file to c. Servers, highlight rules and
+// inventories are appended. Templates are combined but each name can only be
+// defined once, and [defaults] and [structured] can only be set in one file.
+func (c *Config) merge(part Config, file string, docs documents) []Problem {
+	var problems []Problem
+
+	for i, server := range part.Servers {
+		server.File = file
+		server.Table = i
+		c.Servers = append(c.Servers, server)
+	}
+
+	for i, rule := range part.Highlights {
+		c.Highlights = append(c.Highlights, rule)
+		c.highlightOrigins = append(c.highlightOrigins, origin{file: file, index: i})
+	}
+
+	for i, source := range part.Inventory {
+		source.origin = origin{file: file, index: i}
+		c.Inventory = append(c.Inventory, source)
+	}
+
+	names := make([]string, 0, len(part.Templates))
+	for name := range part.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		template := part.Templates[name]
+		template.File = file
+		if existing, ok := c.Templates[name]; ok {
+			problems = append(problems, docs.tableProblem(file, "templates."+name, "", fmt.Sprintf("template %s is already defined in %s", name, existing.File)))
+			continue
+		}
+		if c.Templates == nil {
+			c.Templates = make(map[string]SSHServer)
+		}
+		c.Templates[name] = template
+	}
+
+	if docs[file].hasTable("defaults") {
+		if c.Defaults.File != "" {
+			problems = append(problems, docs.tableProblem(file, "defaults", "", "[defaults] is already set in "+c.Defaults.File))
+		} else {
+			c.Defaults = part.Defaults
+			c.Defaults.File = file
+		}
+	}
+
+	if docs[file].hasTable("structured") {
+		if c.structuredFile != "" {
+			problems = append(problems, docs.tableProblem(file, "structured", "", "[structured] is already set in "+c.structuredFile))
+		} else {
+			c.Structured = part.Structured
+			c.structuredFile = file
+		}
+	}
+
+	return problems
+}
+
+// Files returns the files the config was loaded from, in the order they
+// were merged.
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// WatchPaths returns the files and directories to watch for changes to the
+// config: its files, plus the directories include patterns and a config
+// directory are matched in, so added files are noticed.
+func (c *Config) WatchPaths() []string {
+	return append(append([]string(nil), c.files...), c.watched...)
+}
+
+// ServersFile returns the file new servers are added to: the servers file,
+// or the first file of a config directory.
+func (c *Config) ServersFile() string {
+	if len(c.files) > 0 {
+		return c.files[0]
+	}
+	return c.Path
+}
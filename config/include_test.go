@@ -0,0 +1,122 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// serverNames returns the names of the servers of cfg with the files they
+// are from, relative to dir.
+func serverNames(t *testing.T, dir string, cfg Config) []string {
+	t.Helper()
+	var names []string
+	for _, server := range cfg.Servers {
+		file, err := filepath.Rel(dir, server.File)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, server.Name+"@"+filepath.ToSlash(file))
+	}
+	return names
+}
+
+func serverTable(name string) string {
+	return `
+[[servers]]
+name = "` + name + `"
+host = "` + name + `.example.com"
+password = "secret"
+`
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"servers.toml":       `include = ["conf.d/*.toml", "extra.yaml"]` + "\n" + serverTable("main"),
+		"conf.d/20-db.toml":  serverTable("db"),
+		"conf.d/10-web.toml": serverTable("web") + "\n[defaults]\nuser = \"deploy\"\n",
+		"conf.d/notes.txt":   "not a config file",
+		"extra.yaml":         "servers:\n  - name: extra\n    host: extra.example.com\n    password: secret\n",
+	})
+
+	cfg, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, nil)
+
+	want := []string{"main@servers.toml", "web@conf.d/10-web.toml", "db@conf.d/20-db.toml", "extra@extra.yaml"}
+	if got := serverNames(t, dir, cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("servers = %q, want %q", got, want)
+	}
+	for _, server := range cfg.Servers {
+		if server.User != "deploy" {
+			t.Errorf("server %s: user = %q, want the default from an included file", server.Name, server.User)
+		}
+	}
+	if !slices.Contains(cfg.WatchPaths(), filepath.Join(dir, "conf.d")) {
+		t.Errorf("watch paths %q don't include the glob's directory", cfg.WatchPaths())
+	}
+	if got := cfg.ServersFile(); got != filepath.Join(dir, "servers.toml") {
+		t.Errorf("servers file = %s", got)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"servers.toml": `include = ["a.toml"]` + "\n" + serverTable("main"),
+		"a.toml":       `include = ["b.toml", "a.toml"]` + "\n" + serverTable("a"),
+		"b.toml":       `include = ["servers.toml", "*.toml"]` + "\n" + serverTable("b"),
+	})
+
+	cfg, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, nil)
+
+	want := []string{"main@servers.toml", "a@a.toml", "b@b.toml"}
+	if got := serverNames(t, dir, cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("servers = %q, want %q", got, want)
+	}
+	if len(cfg.Files()) != 3 {
+		t.Errorf("files = %q, want each file once", cfg.Files())
+	}
+}
+
+func TestConfigDirectory(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"servers.d/b.toml": serverTable("b"),
+		"servers.d/a.yaml": "servers:\n  - name: a\n    host: a.example.com\n    password: secret\n",
+	})
+
+	cfg, problems := decodeConfig(filepath.Join(dir, "servers.d"))
+	checkProblems(t, dir, problems, nil)
+
+	want := []string{"a@servers.d/a.yaml", "b@servers.d/b.toml"}
+	if got := serverNames(t, dir, cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("servers = %q, want %q", got, want)
+	}
+}
+
+func TestIncludeProblems(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"servers.toml": `include = ["other.toml", "missing.toml"]` + "\n" + serverTable("web") + `
+[defaults]
+user = "deploy"
+
+[templates.base]
+port = 2222
+`,
+		"other.toml": serverTable("db") + serverTable("web") + `
+[defaults]
+user = "ops"
+
+[templates.base]
+port = 22
+`,
+	})
+
+	_, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, []string{
+		"other.toml:15:1: template base is already defined in servers.toml",
+		"other.toml:12:1: [defaults] is already set in servers.toml",
+		"servers.toml:1:1: include missing.toml: no such file or directory",
+		`other.toml:8:1: server name "web" is already used in servers.toml:4`,
+	})
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+)
+
+// resolve fills in each server's unset values from its template, then from
+// [defaults], so a server's own values win over its template's, which win
+// over the defaults. Lists such as commands and tags are replaced rather than
+// merged. Highlight rules are layered like the global rules: defaults first,
+// then the template, then the server, with later rules replacing earlier ones
+// of the same name.
+func (c *Config) resolve(docs documents) []Problem {
+	var problems []Problem
+
+	if c.Defaults.Name != "" {
+		problems = append(problems, docs.tableProblem(c.Defaults.File, "defaults", "name", "[defaults] can't set a name"))
+	}
+	if c.Defaults.Extends != "" {
+		problems = append(problems, docs.tableProblem(c.Defaults.File, "defaults", "extends", "[defaults] can't extend a template"))
+	}
+	if len(c.Defaults.Hosts) > 0 {
+		problems = append(problems, docs.tableProblem(c.Defaults.File, "defaults", "hosts", "[defaults] can't set hosts"))
+	}
+
+	names := make([]string, 0, len(c.Templates))
+	for name := range c.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	templates := make(map[string]SSHServer, len(c.Templates))
+	for _, name := range names {
+		if c.Templates[name].Name != "" {
+			problems = append(problems, docs.tableProblem(c.Templates[name].File, "templates."+name, "name", fmt.Sprintf("template %s can't set a name", name)))
+		}
+
+		if len(c.Templates[name].Hosts) > 0 {
+			problems = append(problems, docs.tableProblem(c.Templates[name].File, "templates."+name, "hosts", fmt.Sprintf("template %s can't set hosts", name)))
+		}
+
+		template, err := c.template(name, nil)
+		if err != nil {
+			problems = append(problems, docs.tableProblem(c.Templates[name].File, "templates."+name, "extends", err.Error()))
+			continue
+		}
+		templates[name] = template
+	}
+
+	for i := range c.Servers {
+		server := &c.Servers[i]
+
+		// A server whose template is missing or broken still gets the
+		// defaults, so the template is its only problem.
+		if server.Extends != "" {
+			if template, ok := templates[server.Extends]; ok {
+				server.inherit(template, "template "+server.Extends)
+			} else if _, exists := c.Templates[server.Extends]; !exists {
+				problems = append(problems, docs.serverProblem(*server, "extends", fmt.Sprintf("server %s: template %q does not exist", server.Name, server.Extends)))
+			}
+		}
+
+		server.inherit(c.Defaults, "defaults")
+	}
+
+	return problems
+}
+
+// Inherit returns server with the values it inherits from its template and
+// [defaults] filled in, as when it is loaded. Built-in defaults are not
+// applied.
+func (c *Config) Inherit(server SSHServer) (SSHServer, error) {
+	server.Inherited = maps.Clone(server.Inherited)
+	if server.Extends != "" {
+		if _, ok := c.Templates[server.Extends]; !ok {
+			return server, fmt.Errorf("template %q does not exist", server.Extends)
+		}
+		template, err := c.template(server.Extends, nil)
+		if err != nil {
+			return server, err
+		}
+		server.inherit(template, "template "+server.Extends)
+	}
+	server.inherit(c.Defaults, "defaults")
+	return server, nil
+}
+
+// Own returns s without the values it inherited, leaving those set in its
+// own table. Highlight rules are kept as they are.
+func (s SSHServer) Own() SSHServer {
+	for key := range s.Inherited {
+		switch key {
+		case "host":
+			s.Host = ""
+		case "user":
+			s.User = ""
+		case "port":
+			s.Port = 0
+		case "private_key_path":
+			s.PrivateKeyPath = ""
+		case "private_key_passphrase":
+			s.PrivateKeyPassphrase = ""
+		case "password":
+			s.Password = ""
+		case "password_command":
+			s.PasswordCommand = ""
+		case "password_file":
+			s.PasswordFile = ""
+		case "commands":
+			s.Commands = nil
+		case "group":
+			s.Group = ""
+		case "tags":
+			s.Tags = nil
+		case "format":
+			s.Format = ""
+		}
+	}
+	s.Inherited = nil
+	return s
+}
+
+// template returns a template with the templates it extends applied. seen
+// holds the templates already on the chain, to catch cycles.
+func (c *Config) template(name string, seen []string) (SSHServer, error) {
+	for _, s := range seen {
+		if s == name {
+			return SSHServer{}, fmt.Errorf("templates extend each other in a loop: %s", strings.Join(append(seen, name), " -> "))
+		}
+	}
+
+	template, ok := c.Templates[name]
+	if !ok {
+		return SSHServer{}, fmt.Errorf("template %s: template %q does not exist", seen[len(seen)-1], name)
+	}
+
+	if template.Extends != "" {
+		base, err := c.template(template.Extends, append(seen, name))
+		if err != nil {
+			return SSHServer{}, err
+		}
+		template.inherit(base, "template "+template.Extends)
+	}
+	return template, nil
+}
+
+// inherit copies every value that is unset on s from base, recording where
+// each came from.
+func (s *SSHServer) inherit(base SSHServer, source string) {
+	from := func(key string) string {
+		if origin, ok := base.Inherited[key]; ok {
+			return origin
+		}
+		return source
+	}
+	set := func(key string) {
+		if s.Inherited == nil {
+			s.Inherited = make(map[string]string)
+		}
+		s.Inherited[key] = from(key)
+	}
+
+	if s.Host == "" && len(s.Hosts) == 0 && base.Host != "" {
+		s.Host = base.Host
+		set("host")
+	}
+	if s.User == "" && base.User != "" {
+		s.User = base.User
+		set("user")
+	}
+	if s.Port == 0 && base.Port != 0 {
+		s.Port = base.Port
+		set("port")
+	}
+	if s.PrivateKeyPath == "" && base.PrivateKeyPath != "" {
+		s.PrivateKeyPath = base.PrivateKeyPath
+		set("private_key_path")
+	}
+	if s.PrivateKeyPassphrase == "" && base.PrivateKeyPassphrase != "" {
+		s.PrivateKeyPassphrase = base.PrivateKeyPassphrase
+		set("private_key_passphrase")
+	}
+	// The password settings replace each other, so a server that sets any of
+	// them inherits none.
+	if s.Password == "" && s.PasswordCommand == "" && s.PasswordFile == "" {
+		if base.Password != "" {
+			s.Password = base.Password
+			set("password")
+		}
+		if base.PasswordCommand != "" {
+			s.PasswordCommand = base.PasswordCommand
+			set("password_command")
+		}
+		if base.PasswordFile != "" {
+			s.PasswordFile = base.PasswordFile
+			set("password_file")
+		}
+	}
+	if len(s.Commands) == 0 && len(base.Commands) > 0 {
+		s.Commands = base.Commands
+		set("commands")
+	}
+	if s.Group == "" && base.Group != "" {
+		s.Group = base.Group
+		set("group")
+	}
+	if len(s.Tags) == 0 && len(base.Tags) > 0 {
+		s.Tags = base.Tags
+		set("tags")
+	}
+	if s.Format == "" && base.Format != "" {
+		s.Format = base.Format
+		set("format")
+	}
+	if len(base.Highlights) > 0 {
+		s.Highlights = append(append([]HighlightRule(nil), base.Highlights...), s.Highlights...)
+	}
+}
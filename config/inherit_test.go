@@ -0,0 +1,225 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const inheritConfig = `[defaults]
+user = "ops"
+port = 2222
+private_key_path = "/keys/default"
+group = "all"
+tags = ["fleet"]
+
+[[defaults.highlight]]
+name = "defaults"
+pattern = "d"
+
+[templates.base]
+user = "deploy"
+commands = ["uptime"]
+
+[templates.web]
+extends = "base"
+port = 8022
+password = "web-secret"
+format = "json"
+
+[[templates.web.highlight]]
+name = "template"
+pattern = "t"
+
+[[servers]]
+name = "web1"
+host = "web1.example.com"
+extends = "web"
+port = 22
+
+[[servers.highlight]]
+name = "server"
+pattern = "s"
+
+[[servers]]
+name = "db"
+host = "db.example.com"
+password_command = "pass show db"
+tags = ["db"]
+`
+
+func TestInherit(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": inheritConfig})
+	cfg, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	for _, p := range problems {
+		if !p.Warning {
+			t.Fatalf("unexpected problem: %s", p)
+		}
+	}
+	if len(cfg.Servers) != 2 {
+		t.Fatalf("got %d servers, want 2", len(cfg.Servers))
+	}
+
+	tests := []struct {
+		server    SSHServer
+		want      SSHServer
+		inherited map[string]string
+	}{
+		{
+			server: cfg.Servers[0],
+			want: SSHServer{
+				User:           "deploy",
+				Port:           22,
+				PrivateKeyPath: "/keys/default",
+				Password:       "web-secret",
+				Commands:       []string{"uptime"},
+				Group:          "all",
+				Tags:           []string{"fleet"},
+				Format:         "json",
+			},
+			inherited: map[string]string{
+				"user":             "template base",
+				"private_key_path": "defaults",
+				"password":         "template web",
+				"commands":         "template base",
+				"group":            "defaults",
+				"tags":             "defaults",
+				"format":           "template web",
+			},
+		},
+		{
+			server: cfg.Servers[1],
+			want: SSHServer{
+				User:            "ops",
+				Port:            2222,
+				PrivateKeyPath:  "/keys/default",
+				PasswordCommand: "pass show db",
+				Group:           "all",
+				Tags:            []string{"db"},
+			},
+			inherited: map[string]string{
+				"user":             "defaults",
+				"port":             "defaults",
+				"private_key_path": "defaults",
+				"group":            "defaults",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.server.Name, func(t *testing.T) {
+			got := SSHServer{
+				User:            tc.server.User,
+				Port:            tc.server.Port,
+				PrivateKeyPath:  tc.server.PrivateKeyPath,
+				Password:        tc.server.Password,
+				PasswordCommand: tc.server.PasswordCommand,
+				Commands:        tc.server.Commands,
+				Group:           tc.server.Group,
+				Tags:            tc.server.Tags,
+				Format:          tc.server.Format,
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("server = %+v\nwant %+v", got, tc.want)
+			}
+			if !reflect.DeepEqual(tc.server.Inherited, tc.inherited) {
+				t.Errorf("inherited = %v\nwant %v", tc.server.Inherited, tc.inherited)
+			}
+		})
+	}
+
+	var names []string
+	for _, rule := range cfg.Servers[0].Highlights {
+		names = append(names, rule.Name)
+	}
+	if want := []string{"defaults", "template", "server"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("highlight rules = %v, want %v", names, want)
+	}
+}
+
+func TestInheritProblems(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": `[defaults]
+name = "everything"
+password = "secret"
+
+[templates.a]
+extends = "b"
+
+[templates.b]
+extends = "a"
+
+[templates.c]
+hosts = ["web[1-2]"]
+
+[[servers]]
+name = "web"
+host = "web.example.com"
+extends = "missing"
+
+[[servers]]
+name = "db"
+host = "db.example.com"
+user = "postgres"
+extends = "a"
+`})
+
+	_, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, []string{
+		"servers.toml:2:1: [defaults] can't set a name",
+		"servers.toml:6:1: templates extend each other in a loop: a -> b -> a",
+		"servers.toml:9:1: templates extend each other in a loop: b -> a -> b",
+		"servers.toml:12:1: template c can't set hosts",
+		`servers.toml:17:1: server web: template "missing" does not exist`,
+	})
+}
+
+func TestInheritedProblemNamesOrigin(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": `[defaults]
+port = 70000
+password = "secret"
+
+[[servers]]
+name = "web"
+host = "web.example.com"
+`})
+
+	_, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, []string{
+		"servers.toml:5:1: server web: port 70000 is not between 1 and 65535 (from defaults)",
+	})
+}
+
+func TestInheritServer(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": inheritConfig})
+	cfg, _ := decodeConfig(filepath.Join(dir, "servers.toml"))
+
+	added, err := cfg.Inherit(SSHServer{Name: "web2", Host: "web2.example.com", Extends: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.User != "deploy" || added.Port != 8022 || added.Password != "web-secret" || added.Group != "all" {
+		t.Errorf("new server = %+v, want the template's and the defaults' values", added)
+	}
+
+	// A loaded server loses what it inherited and gets it back.
+	loaded := cfg.Servers[0]
+	own := loaded.Own()
+	if own.User != "" || own.Password != "" || own.Host != "web1.example.com" || own.Port != 22 || own.Inherited != nil {
+		t.Errorf("own values = %+v", own)
+	}
+	if loaded.Inherited == nil {
+		t.Error("Own changed the server's inherited values")
+	}
+	again, err := cfg.Inherit(own)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Highlights, loaded.Highlights = nil, nil
+	if !reflect.DeepEqual(again, loaded) {
+		t.Errorf("inherited again = %+v\nwant %+v", again, loaded)
+	}
+
+	if _, err := cfg.Inherit(SSHServer{Name: "cache", Extends: "missing"}); err == nil {
+		t.Error("inheriting from a missing template succeeded")
+	}
+}
@@ -0,0 +1,453 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// inventoryTimeout limits how long an inventory command may run.
+const inventoryTimeout = 30 * time.Second
+
+// InventorySource supplies servers from outside the servers file: the JSON
+// printed by a command, or a JSON, YAML or Ansible INI file. JSON and YAML
+// inventories hold a list of servers with the same keys as [[servers]],
+// either at the top level or under "servers".
+type InventorySource struct {
+	Name    string   `toml:"name"`
+	Command []string `toml:"command,omitempty"`
+	File    string   `toml:"file,omitempty"`
+
+	// Format is json, yaml or ini. For files it defaults to the extension.
+	Format string `toml:"format,omitempty"`
+
+	// Extends is the template applied to servers that don't name their own.
+	Extends string `toml:"extends,omitempty"`
+
+	// Refresh is how often the inventory is loaded again while running, as a
+	// duration such as "5m". Inventories are only loaded at start when unset.
+	Refresh string `toml:"refresh,omitempty"`
+
+	origin origin
+}
+
+func (s InventorySource) label() string {
+	if s.Name != "" {
+		return "inventory " + s.Name
+	}
+	if s.File != "" {
+		return "inventory " + s.File
+	}
+	return "inventory " + strings.Join(s.Command, " ")
+}
+
+// RefreshInterval returns how often the inventory should be loaded again, or
+// 0 if it shouldn't.
+func (s InventorySource) RefreshInterval() time.Duration {
+	d, err := time.ParseDuration(s.Refresh)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// inventoryFormats are the formats an inventory can be in.
+var inventoryFormats = []string{"json", "yaml", "yml", "ini"}
+
+// InventoryFailed reports whether the inventory the servers labelled source
+// came from failed to load.
+func (c *Config) InventoryFailed(source string) bool {
+	_, ok := c.failedInventory[source]
+	return ok
+}
+
+// InventoryError returns why the first inventory that failed to load failed,
+// or nil if all of them loaded.
+func (c *Config) InventoryError() error {
+	for _, source := range c.Inventory {
+		if err, ok := c.failedInventory[source.label()]; ok {
+			return fmt.Errorf("%s: %w", source.label(), err)
+		}
+	}
+	return nil
+}
+
+// InventoryRefresh returns the shortest refresh interval of the config's
+// inventories, or 0 if none refresh.
+func (c *Config) InventoryRefresh() time.Duration {
+	var interval time.Duration
+	for _, source := range c.Inventory {
+		if d := source.RefreshInterval(); d > 0 && (interval == 0 || d < interval) {
+			interval = d
+		}
+	}
+	return interval
+}
+
+// loadInventory appends the servers of every inventory source. Paths and
+// commands are relative to the directory of the file declaring the source.
+//
+// A source whose command or file fails is only a warning, so the other
+// servers still load; it is listed by InventoryFailed so the servers it
+// supplied before can be kept.
+func (c *Config) loadInventory(docs documents) []Problem {
+	var problems []Problem
+	c.failedInventory = make(map[string]error)
+
+	for _, source := range c.Inventory {
+		report := func(key string, err error) {
+			problems = append(problems, docs.problem(source.origin.file, "inventory", source.origin.index, key, source.label()+": "+err.Error()))
+		}
+
+		if source.Refresh != "" {
+			if d, err := time.ParseDuration(source.Refresh); err != nil || d < 0 {
+				report("refresh", fmt.Errorf("invalid refresh interval %q", source.Refresh))
+			}
+		}
+		switch {
+		case len(source.Command) > 0 && source.File != "":
+			report("command", fmt.Errorf("set either command or file, not both"))
+			continue
+		case len(source.Command) == 0 && source.File == "":
+			report("", fmt.Errorf("either command or file is required"))
+			continue
+		case source.Format != "" && !slices.Contains(inventoryFormats, strings.ToLower(source.Format)):
+			report("format", fmt.Errorf("unknown inventory format %q, expected json, yaml or ini", source.Format))
+			continue
+		}
+
+		servers, err := source.load(filepath.Dir(source.origin.file))
+		if err != nil {
+			key := "file"
+			if len(source.Command) > 0 {
+				key = "command"
+			}
+			report(key, err)
+			problems[len(problems)-1].Warning = true
+			c.failedInventory[source.label()] = err
+			continue
+		}
+
+		for _, server := range servers {
+			server.File = source.origin.file
+			server.Table = -1
+			server.Generated = true
+			server.Source = source.label()
+			if server.Extends == "" {
+				server.Extends = source.Extends
+			}
+			c.Servers = append(c.Servers, server)
+		}
+	}
+
+	return problems
+}
+
+// load runs the source's command or reads its file. Sources are checked to
+// have exactly one of them before.
+func (s InventorySource) load(dir string) ([]SSHServer, error) {
+	if len(s.Command) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), inventoryTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+		cmd.Dir = dir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("command failed: %w: %s", err, msg)
+			}
+			return nil, fmt.Errorf("command failed: %w", err)
+		}
+
+		format := s.Format
+		if format == "" {
+			format = "json"
+		}
+		return decodeInventory(out, format)
+	}
+
+	path, err := expandHome(s.File)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Unwrap(err)
+	}
+
+	format := s.Format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	return decodeInventory(data, format)
+}
+
+func decodeInventory(data []byte, format string) ([]SSHServer, error) {
+	var raw any
+	switch strings.ToLower(format) {
+	case "json":
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	case "ini":
+		return parseAnsibleINI(data)
+	default:
+		return nil, fmt.Errorf("unknown inventory format %q, expected json, yaml or ini", format)
+	}
+
+	if m, ok := raw.(map[string]any); ok {
+		raw = m["servers"]
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list of servers")
+	}
+
+	var decoded struct {
+		Servers []SSHServer `toml:"servers"`
+	}
+	if err := decodeGeneric(map[string]any{"servers": list}, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded.Servers, nil
+}
+
+// decodeGeneric decodes a value parsed from JSON or YAML into v using its
+// TOML tags, rejecting unknown keys.
+func decodeGeneric(value any, v any) error {
+	data, err := toml.Marshal(normalizeGeneric(value))
+	if err != nil {
+		return err
+	}
+
+	decoder := toml.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(v)
+
+	var strict *toml.StrictMissingError
+	var decodeErr *toml.DecodeError
+	switch {
+	case errors.As(err, &strict):
+		var keys []string
+		for _, e := range strict.Errors {
+			keys = append(keys, strings.Join(e.Key(), "."))
+		}
+		return fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+	case errors.As(err, &decodeErr):
+		return errors.New(strings.TrimPrefix(decodeErr.Error(), "toml: "))
+	}
+	return err
+}
+
+// normalizeGeneric converts whole-number floats, which is how JSON numbers
+// decode, to integers so they can fill integer fields.
+func normalizeGeneric(value any) any {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeGeneric(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeGeneric(item)
+		}
+	}
+	return value
+}
+
+// parseAnsibleINI reads hosts from an Ansible INI inventory. Each host
+// becomes a server named after its alias, in the group of the first section
+// it appears in and tagged with every group, including the groups those are
+// children of in [group:children] sections. ansible_host, ansible_user,
+// ansible_port, ansible_ssh_private_key_file and ansible_password (or
+// ansible_ssh_pass) are used, including from [group:vars] sections of the
+// host's groups and their parents. Ranges such as web[01:03] are expanded.
+func parseAnsibleINI(data []byte) ([]SSHServer, error) {
+	type host struct {
+		vars   map[string]string
+		groups []string
+	}
+
+	hosts := make(map[string]*host)
+	var order []string
+	groupVars := make(map[string]map[string]string)
+	// parents maps each group to the groups it is a child of.
+	parents := make(map[string][]string)
+
+	section, kind := "", ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind, _ = strings.Cut(line[1:len(line)-1], ":")
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value", lineNo)
+			}
+			if groupVars[section] == nil {
+				groupVars[section] = make(map[string]string)
+			}
+			groupVars[section][strings.TrimSpace(key)] = unquoteINI(strings.TrimSpace(value))
+			continue
+		case "children":
+			if !slices.Contains(parents[fields[0]], section) {
+				parents[fields[0]] = append(parents[fields[0]], section)
+			}
+			continue
+		}
+
+		names, err := expandHostPattern(ansibleRange(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		vars := make(map[string]string)
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value, got %q", lineNo, field)
+			}
+			vars[key] = unquoteINI(value)
+		}
+
+		for _, name := range names {
+			h, ok := hosts[name]
+			if !ok {
+				h = &host{vars: make(map[string]string)}
+				hosts[name] = h
+				order = append(order, name)
+			}
+			for key, value := range vars {
+				h.vars[key] = value
+			}
+			if section != "" && section != "all" && section != "ungrouped" {
+				h.groups = append(h.groups, section)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	servers := make([]SSHServer, 0, len(order))
+	for _, name := range order {
+		h := hosts[name]
+
+		// A host is in the parents of its groups too, after its own groups.
+		// Groups that are children of each other are only listed once.
+		tags := slices.Clone(h.groups)
+		for i := 0; i < len(tags); i++ {
+			for _, parent := range parents[tags[i]] {
+				if parent != "all" && parent != "ungrouped" && !slices.Contains(tags, parent) {
+					tags = append(tags, parent)
+				}
+			}
+		}
+
+		// Host variables win over group variables, groups listed earlier win
+		// over later ones, child groups win over their parents and every
+		// group wins over all.
+		vars := make(map[string]string)
+		groups := append(slices.Clone(tags), "all")
+		for i := len(groups) - 1; i >= 0; i-- {
+			for key, value := range groupVars[groups[i]] {
+				vars[key] = value
+			}
+		}
+		for key, value := range h.vars {
+			vars[key] = value
+		}
+
+		server := SSHServer{
+			Name:           name,
+			Host:           name,
+			User:           vars["ansible_user"],
+			PrivateKeyPath: vars["ansible_ssh_private_key_file"],
+			Password:       vars["ansible_password"],
+			Tags:           tags,
+		}
+		if server.Password == "" {
+			server.Password = vars["ansible_ssh_pass"]
+		}
+		if host := vars["ansible_host"]; host != "" {
+			server.Host = host
+		}
+		if port := vars["ansible_port"]; port != "" {
+			n, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, fmt.Errorf("host %s: invalid ansible_port %q", name, port)
+			}
+			server.Port = n
+		}
+		if len(h.groups) > 0 {
+			server.Group = h.groups[0]
+		}
+		servers = append(servers, server)
+	}
+
+	return servers, nil
+}
+
+// ansibleRange rewrites Ansible's web[01:03] ranges to the web[01-03] form
+// used by hosts.
+func ansibleRange(pattern string) string {
+	var b strings.Builder
+	inBracket := false
+	for _, r := range pattern {
+		switch {
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case r == ':' && inBracket:
+			r = '-'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func unquoteINI(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
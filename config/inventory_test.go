@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnsibleINI(t *testing.T) {
+	servers, err := parseAnsibleINI([]byte(`# Production
+bastion ansible_host=10.0.0.1
+
+[web]
+web[01:02] ansible_user=www
+
+[db]
+db1 ansible_port=2222
+
+[prod:children]
+web
+db
+
+[europe:children]
+prod
+
+[all:vars]
+ansible_user=admin
+ansible_ssh_private_key_file=/keys/all
+
+[prod:vars]
+ansible_user=deploy
+ansible_password="prod secret"
+
+[europe:vars]
+ansible_port=2200
+ansible_ssh_private_key_file=/keys/europe
+
+[db:vars]
+ansible_user=postgres
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SSHServer{
+		{Name: "bastion", Host: "10.0.0.1", User: "admin", PrivateKeyPath: "/keys/all"},
+		{Name: "web01", Host: "web01", User: "www", Port: 2200, PrivateKeyPath: "/keys/europe", Password: "prod secret", Group: "web", Tags: []string{"web", "prod", "europe"}},
+		{Name: "web02", Host: "web02", User: "www", Port: 2200, PrivateKeyPath: "/keys/europe", Password: "prod secret", Group: "web", Tags: []string{"web", "prod", "europe"}},
+		{Name: "db1", Host: "db1", User: "postgres", Port: 2222, PrivateKeyPath: "/keys/europe", Password: "prod secret", Group: "db", Tags: []string{"db", "prod", "europe"}},
+	}
+	if !reflect.DeepEqual(servers, want) {
+		t.Errorf("servers:\n%+v\nwant:\n%+v", servers, want)
+	}
+}
+
+func TestParseAnsibleINIChildrenLoop(t *testing.T) {
+	servers, err := parseAnsibleINI([]byte(`[a]
+host1
+
+[a:children]
+b
+
+[b:children]
+a
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || !reflect.DeepEqual(servers[0].Tags, []string{"a", "b"}) {
+		t.Errorf("servers = %+v, want host1 tagged a and b", servers)
+	}
+}
+
+func TestParseAnsibleINIProblems(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"[web:vars]\nansible_user\n", "line 2: expected key=value"},
+		{"web1 ansible_user\n", `line 1: expected key=value, got "ansible_user"`},
+		{"web[3:1]\n", `line 1: host pattern "web[3-1]": range [3-1] runs backwards`},
+		{"web1 ansible_port=ssh\n", `host web1: invalid ansible_port "ssh"`},
+	}
+	for _, tc := range tests {
+		_, err := parseAnsibleINI([]byte(tc.input))
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("parseAnsibleINI(%q) = %v, want %q", tc.input, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Migration upgrades a document to Version from the version before it.
+// Apply edits the document in place and may be nil when the version only
+// marks a change that older files are already compatible with.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(doc *Document) error
+}
+
+// Migrations is the chain of migrations for one kind of file, oldest first.
+// Files without a version key are at version 0, and the version of the last
+// migration is the one files are written with.
+type Migrations []Migration
+
+// ServerMigrations upgrade servers files.
+var ServerMigrations = Migrations{
+	// Version 1 is the format the version key was added in. Files without
+	// one are already valid in it, so there is nothing to apply.
+	{Version: 1, Description: "add the version key"},
+}
+
+// Current returns the version files are written with.
+func (m Migrations) Current() int {
+	if len(m) == 0 {
+		return 0
+	}
+	return m[len(m)-1].Version
+}
+
+// documentVersion returns the version key of doc, or 0 when it has none.
+func documentVersion(doc *Document) (int, error) {
+	var versioned struct {
+		Version int `toml:"version"`
+	}
+	if err := toml.Unmarshal(doc.Bytes(), &versioned); err != nil {
+		// The file is not valid; decoding it reports why.
+		return 0, nil
+	}
+	if versioned.Version < 0 {
+		return 0, fmt.Errorf("version %d is not valid", versioned.Version)
+	}
+	return versioned.Version, nil
+}
+
+// migrate upgrades doc in memory to the current version. It returns the
+// version doc was at and whether any migration edited it. Files from a newer
+// version of the app are an error, as their settings could be silently
+// ignored.
+func (m Migrations) migrate(doc *Document) (version int, edited bool, err error) {
+	version, err = documentVersion(doc)
+	if err != nil {
+		return 0, false, err
+	}
+	if version > m.Current() {
+		return version, false, fmt.Errorf("version %d is newer than this version of ssh-thing supports (%d); upgrade ssh-thing to load it", version, m.Current())
+	}
+
+	for _, migration := range m {
+		if migration.Version <= version || migration.Apply == nil {
+			continue
+		}
+		if err := migration.Apply(doc); err != nil {
+			return version, edited, fmt.Errorf("failed to upgrade to version %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		edited = true
+	}
+	return version, edited, nil
+}
+
+// Decode decodes a file of any supported format into v after upgrading it
+// in memory. Like the servers file it is decoded strictly, so a misspelled
+// key is an error rather than silently ignored.
+func (m Migrations) Decode(path string, data []byte, v any) error {
+	doc, err := parseFile(path, data)
+	if err != nil {
+		return err
+	}
+	if _, _, err := m.migrate(doc); err != nil {
+		return err
+	}
+
+	decoder := toml.NewDecoder(bytes.NewReader(doc.Bytes()))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(v)
+
+	var strict *toml.StrictMissingError
+	if errors.As(err, &strict) {
+		var unknown []string
+		for _, e := range strict.Errors {
+			line, _ := doc.sourcePosition(e.Position())
+			unknown = append(unknown, fmt.Sprintf("line %d: unknown key %s", line, strings.Join(e.Key(), ".")))
+		}
+		return errors.New(strings.Join(unknown, "; "))
+	}
+	return err
+}
+
+// Upgrade rewrites the file at path in the current version, after copying
+// it to a backup next to it. It returns the version the file was at and the
+// backup's path, which is empty when the file was already current.
+func (m Migrations) Upgrade(path string) (int, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	doc, err := parseFile(path, data)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	version, _, err := m.migrate(doc)
+	if err != nil {
+		return version, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if version == m.Current() {
+		return version, "", nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := os.WriteFile(backup, data, info.Mode().Perm()); err != nil {
+		return version, "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	doc.setVersion(m.Current())
+	data, err = encodeFile(path, data, doc)
+	if err != nil {
+		return version, backup, fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return version, backup, writeConfigFile(path, data)
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testMigrations renames the hostname key of servers to host in version 2.
+var testMigrations = Migrations{
+	{Version: 1, Description: "add the version key"},
+	{Version: 2, Description: "rename hostname to host", Apply: func(doc *Document) error {
+		for i, line := range doc.lines {
+			if rest, ok := strings.CutPrefix(line, "hostname ="); ok {
+				doc.lines[i] = "host =" + rest
+			}
+		}
+		return nil
+	}},
+}
+
+type testServers struct {
+	Version int `toml:"version"`
+	Servers []struct {
+		Name string `toml:"name"`
+		Host string `toml:"host"`
+	} `toml:"servers"`
+}
+
+func TestMigrationsDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		input string
+		err   string
+	}{
+		{name: "unversioned", file: "servers.toml", input: "[[servers]]\nname = \"web\"\nhostname = \"web.example.com\"\n"},
+		{name: "version 1", file: "servers.toml", input: "version = 1\n\n[[servers]]\nname = \"web\"\nhostname = \"web.example.com\"\n"},
+		{name: "current", file: "servers.toml", input: "version = 2\n\n[[servers]]\nname = \"web\"\nhost = \"web.example.com\"\n"},
+		{name: "yaml", file: "servers.yaml", input: "servers:\n  - name: web\n    hostname: web.example.com\n"},
+		{name: "newer", file: "servers.toml", input: "version = 3\n", err: "version 3 is newer than this version of ssh-thing supports (2)"},
+		{name: "negative", file: "servers.toml", input: "version = -1\n", err: "version -1 is not valid"},
+		{name: "not yet migrated key", file: "servers.toml", input: "version = 2\n\n[[servers]]\nname = \"web\"\nhostname = \"web.example.com\"\n", err: "line 5: unknown key servers.hostname"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var decoded testServers
+			err := testMigrations.Decode(tc.file, []byte(tc.input), &decoded)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Decode = %v, want an error containing %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded.Servers) != 1 || decoded.Servers[0].Host != "web.example.com" {
+				t.Errorf("decoded = %+v", decoded)
+			}
+		})
+	}
+}
+
+func TestMigrationsUpgrade(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		input string
+		want  string
+	}{
+		{
+			name: "toml",
+			file: "servers.toml",
+			input: `# Team servers
+
+[[servers]]
+name = "web" # the frontend
+hostname = "web.example.com"
+`,
+			want: `# Team servers
+
+version = 2
+
+[[servers]]
+name = "web" # the frontend
+host = "web.example.com"
+`,
+		},
+		{
+			name: "toml with a version",
+			file: "servers.toml",
+			input: `version = 1 # upgraded by hand
+
+[[servers]]
+name = "web"
+hostname = "web.example.com"
+`,
+			want: `version = 2 # upgraded by hand
+
+[[servers]]
+name = "web"
+host = "web.example.com"
+`,
+		},
+		{
+			name: "yaml",
+			file: "servers.yaml",
+			input: `# Team servers
+servers:
+  - name: web # the frontend
+    hostname: web.example.com
+`,
+			// The comment belongs to the servers key, so version goes above it.
+			want: `version: 2
+# Team servers
+servers:
+  - name: web # the frontend
+    host: web.example.com
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeConfig(t, map[string]string{tc.file: tc.input})
+			path := filepath.Join(dir, tc.file)
+
+			version, backup, err := testMigrations.Upgrade(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantVersion := 0
+			if strings.HasPrefix(tc.input, "version") {
+				wantVersion = 1
+			}
+			if version != wantVersion {
+				t.Errorf("version = %d, want %d", version, wantVersion)
+			}
+
+			if want := fmt.Sprintf("%s.v%d.bak", path, wantVersion); backup != want {
+				t.Errorf("backup = %s, want %s", backup, want)
+			}
+			if data, err := os.ReadFile(backup); err != nil || string(data) != tc.input {
+				t.Errorf("backup holds %q, %v, want the original file", data, err)
+			}
+			if data, err := os.ReadFile(path); err != nil || string(data) != tc.want {
+				t.Errorf("upgraded file:\n%s\nwant:\n%s", data, tc.want)
+			}
+
+			// The file is current now, so nothing is done.
+			version, backup, err = testMigrations.Upgrade(path)
+			if err != nil || version != 2 || backup != "" {
+				t.Errorf("second Upgrade = %d, %q, %v, want 2 without a backup", version, backup, err)
+			}
+		})
+	}
+}
+
+func TestMigrationsUpgradeRefuses(t *testing.T) {
+	failing := append(Migrations{}, testMigrations...)
+	failing = append(failing, Migration{Version: 3, Description: "always fail", Apply: func(*Document) error {
+		return errors.New("no way")
+	}})
+
+	tests := []struct {
+		name       string
+		migrations Migrations
+		input      string
+		err        string
+	}{
+		{"newer version", testMigrations, "version = 3\n", "version 3 is newer than this version of ssh-thing supports (2)"},
+		{"failing migration", failing, "version = 1\n", "failed to upgrade to version 3 (always fail): no way"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeConfig(t, map[string]string{"servers.toml": tc.input})
+			path := filepath.Join(dir, "servers.toml")
+
+			if _, _, err := tc.migrations.Upgrade(path); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("Upgrade = %v, want an error containing %q", err, tc.err)
+			}
+			if data, err := os.ReadFile(path); err != nil || string(data) != tc.input {
+				t.Errorf("file was changed to %q, %v", data, err)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("a backup was written: %v", entries)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRefusesNewerVersion(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": `version = 99
+
+[[servers]]
+name = "web"
+host = "web.example.com"
+password = "secret"
+`})
+
+	_, problems := decodeConfig(filepath.Join(dir, "servers.toml"))
+	checkProblems(t, dir, problems, []string{
+		"servers.toml:1:1: version 99 is newer than this version of ssh-thing supports (1); upgrade ssh-thing to load it",
+	})
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/toyz/ssh-thing/util"
+)
+
+// EnvConfigDir names the environment variable that points at a config
+// directory, which is searched before any other.
+const EnvConfigDir = "SSH_THING_CONFIG"
+
+// appDir is the name of the app's directory in the config directories.
+const appDir = "ssh-thing"
+
+// serversFiles are the names the servers file is looked for under, in order.
+var serversFiles = append(FileNames("servers"), FileNames("config")...)
+
+// SearchDirs returns the directories config files are looked for in, in
+// order: $SSH_THING_CONFIG, the working directory, the user config directory
+// and the system config directories from $XDG_CONFIG_DIRS.
+func SearchDirs() []string {
+	var dirs []string
+	if dir := os.Getenv(EnvConfigDir); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, ".")
+
+	if dir, err := UserConfigDir(); err == nil {
+		dirs = append(dirs, dir)
+	}
+
+	if util.IsWindows() {
+		return dirs
+	}
+	systemDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if systemDirs == "" {
+		systemDirs = "/etc/xdg"
+	}
+	for _, dir := range filepath.SplitList(systemDirs) {
+		// Relative paths are invalid in XDG variables and are ignored.
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, filepath.Join(dir, appDir))
+		}
+	}
+	return dirs
+}
+
+// UserConfigDir returns the app's directory in $XDG_CONFIG_HOME, or in the
+// platform's user config directory when that is not set.
+func UserConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDir), nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appDir), nil
+}
+
+// ConfigDir returns the directory new config and state files are written
+// to: $SSH_THING_CONFIG when it is set, and the user config directory
+// otherwise. It may not exist yet; it is created when a file is written.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv(EnvConfigDir); dir != "" {
+		return dir, nil
+	}
+	return UserConfigDir()
+}
+
+// FindFile returns the first file found in the search directories under any
+// of names. Each directory is checked for every name before the next one.
+func FindFile(names ...string) (string, bool) {
+	for _, dir := range SearchDirs() {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// FilePath returns where the config or state file called by any of names
+// is read from: the first one found in the search directories, or otherwise
+// the path in ConfigDir it is created at under the first name.
+func FilePath(names ...string) (string, error) {
+	if path, ok := FindFile(names...); ok {
+		return path, nil
+	}
+
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, names[0]), nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// secretTimeout limits how long a password command may run.
+const secretTimeout = 30 * time.Second
+
+// VaultPrefix marks a password or key passphrase kept in the vault, as in
+// password = "vault:prod-db".
+const VaultPrefix = "vault:"
+
+// SecretStore looks up the secrets referred to with VaultPrefix.
+type SecretStore interface {
+	Secret(name string) (string, error)
+}
+
+// Interpolate replaces each ${NAME} in s with the value of the environment
+// variable NAME. $${ stands for a literal ${. A variable that is not set is
+// an error rather than an empty string, so a typo doesn't go unnoticed.
+func Interpolate(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			b.WriteString("${")
+			i += 2
+
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${")
+			}
+			name := s[i+2 : i+end]
+			if name == "" {
+				return "", fmt.Errorf("empty variable name in ${}")
+			}
+
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			b.WriteString(value)
+			i += end
+
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// setting is a string setting of a server and its key in the file.
+type setting struct {
+	key   string
+	value *string
+}
+
+// connectionSettings returns the connection settings that are interpolated.
+// Only these are: name, group, tags and format are used when the file is
+// loaded rather than when connecting, so they are taken as written.
+func (s *SSHServer) connectionSettings() []setting {
+	settings := []setting{
+		{"host", &s.Host},
+		{"user", &s.User},
+		{"private_key_path", &s.PrivateKeyPath},
+		{"password", &s.Password},
+		{"password_command", &s.PasswordCommand},
+		{"password_file", &s.PasswordFile},
+		{"private_key_passphrase", &s.PrivateKeyPassphrase},
+	}
+	for i := range s.Commands {
+		settings = append(settings, setting{"commands", &s.Commands[i]})
+	}
+	return settings
+}
+
+// Resolve returns the settings to connect with: environment variables in
+// the connection settings are interpolated, the password is read from
+// password_command or password_file, and vault references are looked up in
+// store. It is called when connecting, so secrets are only held by the
+// connection and never saved with the config.
+func (s SSHServer) Resolve(store SecretStore) (SSHServer, error) {
+	resolved := s
+	resolved.Commands = append([]string(nil), s.Commands...)
+
+	for _, setting := range resolved.connectionSettings() {
+		value, err := Interpolate(*setting.value)
+		if err != nil {
+			return s, fmt.Errorf("%s: %w", setting.key, err)
+		}
+		*setting.value = value
+	}
+
+	keyPath, err := expandHome(resolved.PrivateKeyPath)
+	if err != nil {
+		return s, err
+	}
+	resolved.PrivateKeyPath = keyPath
+
+	switch {
+	case resolved.PasswordCommand != "":
+		password, err := runPasswordCommand(resolved.PasswordCommand)
+		if err != nil {
+			return s, err
+		}
+		resolved.Password = password
+
+	case resolved.PasswordFile != "":
+		path, err := expandHome(resolved.PasswordFile)
+		if err != nil {
+			return s, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return s, fmt.Errorf("password_file: %w", errors.Unwrap(err))
+		}
+		resolved.Password = firstLine(data)
+	}
+
+	for _, setting := range []setting{{"password", &resolved.Password}, {"private_key_passphrase", &resolved.PrivateKeyPassphrase}} {
+		name, ok := strings.CutPrefix(*setting.value, VaultPrefix)
+		if !ok {
+			continue
+		}
+		if store == nil {
+			return s, fmt.Errorf("%s: no vault to look up %q in", setting.key, name)
+		}
+		secret, err := store.Secret(name)
+		if err != nil {
+			return s, fmt.Errorf("%s: %w", setting.key, err)
+		}
+		*setting.value = secret
+	}
+
+	return resolved, nil
+}
+
+// runPasswordCommand runs command with the shell, sh or cmd on Windows, and
+// returns the first line of its output, which is how pass and similar tools
+// print a password.
+func runPasswordCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("password_command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("password_command failed: %w", err)
+	}
+
+	password := firstLine(out)
+	if password == "" {
+		return "", fmt.Errorf("password_command printed no password")
+	}
+	return password, nil
+}
+
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSuffix(line, "\r")
+}
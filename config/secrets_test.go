@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("ST_HOST", "db.example.com")
+	t.Setenv("ST_ENV", "prod")
+	t.Setenv("ST_EMPTY", "")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  string
+	}{
+		{"no variables", "plain $text", "plain $text", ""},
+		{"variable", "${ST_HOST}", "db.example.com", ""},
+		{"several variables", "${ST_ENV}-${ST_HOST}:22", "prod-db.example.com:22", ""},
+		{"empty variable", "a${ST_EMPTY}b", "ab", ""},
+		{"escape", "$${ST_HOST}", "${ST_HOST}", ""},
+		{"escape next to variable", "$${x}${ST_ENV}", "${x}prod", ""},
+		{"dollar without brace", "$ST_HOST", "$ST_HOST", ""},
+		{"unset variable", "${ST_UNSET}", "", "environment variable ST_UNSET is not set"},
+		{"unterminated", "host-${ST_HOST", "", "unterminated ${"},
+		{"empty name", "${}", "", "empty variable name"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Interpolate(tc.in)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Interpolate(%q) = %q, %v, want an error containing %q", tc.in, got, err, tc.err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("Interpolate(%q) = %q, %v, want %q", tc.in, got, err, tc.want)
+			}
+		})
+	}
+}
+
+// mapStore is a SecretStore backed by a map.
+type mapStore map[string]string
+
+func (s mapStore) Secret(name string) (string, error) {
+	secret, ok := s[name]
+	if !ok {
+		return "", fmt.Errorf("no secret named %q in the vault", name)
+	}
+	return secret, nil
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("ST_ENV", "prod")
+	t.Setenv("ST_USER", "deploy")
+
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("from-file\r\nsecond line\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := mapStore{"prod-db": "from-vault", "key": "key-secret"}
+
+	tests := []struct {
+		name   string
+		server SSHServer
+		store  SecretStore
+		unix   bool
+		check  func(t *testing.T, resolved SSHServer)
+		err    string
+	}{
+		{
+			name:   "environment variables",
+			server: SSHServer{Host: "db.${ST_ENV}.example.com", User: "${ST_USER}", Commands: []string{"tail -f /var/log/${ST_ENV}.log"}},
+			check: func(t *testing.T, resolved SSHServer) {
+				if resolved.Host != "db.prod.example.com" || resolved.User != "deploy" || resolved.Commands[0] != "tail -f /var/log/prod.log" {
+					t.Errorf("resolved = %+v", resolved)
+				}
+			},
+		},
+		{
+			name:   "unset variable",
+			server: SSHServer{Host: "${ST_UNSET}"},
+			err:    "host: environment variable ST_UNSET is not set",
+		},
+		{
+			name:   "unset variable in a command",
+			server: SSHServer{Host: "h", Commands: []string{"ok", "echo ${ST_UNSET}"}},
+			err:    "commands: environment variable ST_UNSET is not set",
+		},
+		{
+			name:   "password file",
+			server: SSHServer{PasswordFile: passwordFile},
+			check: func(t *testing.T, resolved SSHServer) {
+				if resolved.Password != "from-file" {
+					t.Errorf("password = %q, want from-file", resolved.Password)
+				}
+			},
+		},
+		{
+			name:   "missing password file",
+			server: SSHServer{PasswordFile: filepath.Join(dir, "missing")},
+			err:    "password_file: no such file or directory",
+		},
+		{
+			name:   "password command",
+			server: SSHServer{PasswordCommand: "echo from-command; echo second line"},
+			unix:   true,
+			check: func(t *testing.T, resolved SSHServer) {
+				if resolved.Password != "from-command" {
+					t.Errorf("password = %q, want from-command", resolved.Password)
+				}
+			},
+		},
+		{
+			name:   "password command takes precedence over password file",
+			server: SSHServer{PasswordCommand: "echo from-command", PasswordFile: passwordFile},
+			unix:   true,
+			check: func(t *testing.T, resolved SSHServer) {
+				if resolved.Password != "from-command" {
+					t.Errorf("password = %q, want from-command", resolved.Password)
+				}
+			},
+		},
+		{
+			name:   "failing password command",
+			server: SSHServer{PasswordCommand: "echo locked >&2; exit 3"},
+			unix:   true,
+			err:    "password_command failed: exit status 3: locked",
+		},
+		{
+			name:   "silent password command",
+			server: SSHServer{PasswordCommand: "true"},
+			unix:   true,
+			err:    "password_command printed no password",
+		},
+		{
+			name:   "vault references",
+			server: SSHServer{Password: "vault:prod-db", PrivateKeyPassphrase: "vault:key"},
+			store:  store,
+			check: func(t *testing.T, resolved SSHServer) {
+				if resolved.Password != "from-vault" || resolved.PrivateKeyPassphrase != "key-secret" {
+					t.Errorf("password = %q, passphrase = %q", resolved.Password, resolved.PrivateKeyPassphrase)
+				}
+			},
+		},
+		{
+			name:   "vault reference from a password file",
+			server: SSHServer{PasswordFile: writeFile(t, dir, "vaultref", "vault:prod-db\n")},
+			store:  store,
+			check: func(t *testing.T, resolved SSHServer) {
+				if resolved.Password != "from-vault" {
+					t.Errorf("password = %q, want from-vault", resolved.Password)
+				}
+			},
+		},
+		{
+			name:   "unknown vault secret",
+			server: SSHServer{Password: "vault:missing"},
+			store:  store,
+			err:    `password: no secret named "missing" in the vault`,
+		},
+		{
+			name:   "vault reference without a vault",
+			server: SSHServer{PrivateKeyPassphrase: "vault:key"},
+			err:    `private_key_passphrase: no vault to look up "key" in`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.unix && runtime.GOOS == "windows" {
+				t.Skip("uses sh")
+			}
+
+			resolved, err := tc.server.Resolve(tc.store)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Resolve = %v, want an error containing %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve = %v", err)
+			}
+			tc.check(t, resolved)
+		})
+	}
+}
+
+func TestResolveKeepsServer(t *testing.T) {
+	t.Setenv("ST_ENV", "prod")
+
+	server := SSHServer{Host: "${ST_ENV}", Commands: []string{"echo ${ST_ENV}"}}
+	if _, err := server.Resolve(nil); err != nil {
+		t.Fatal(err)
+	}
+	if server.Host != "${ST_ENV}" || server.Commands[0] != "echo ${ST_ENV}" {
+		t.Errorf("Resolve changed the server: %+v", server)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/toyz/ssh-thing/logs"
+)
+
+// StructuredConfig controls how structured log lines are rendered. Field
+// lists are tried in order; empty lists fall back to the common names in
+// logs.DefaultFieldMapping.
+type StructuredConfig struct {
+	TimeFields    []string          `toml:"time_fields,omitempty"`
+	LevelFields   []string          `toml:"level_fields,omitempty"`
+	MessageFields []string          `toml:"message_fields,omitempty"`
+	TimeColor     string            `toml:"time_color,omitempty"`
+	KeyColor      string            `toml:"key_color,omitempty"`
+	MessageColor  string            `toml:"message_color,omitempty"`
+	LevelColors   map[string]string `toml:"level_colors,omitempty"`
+}
+
+func (s StructuredConfig) FieldMapping() logs.FieldMapping {
+	mapping := logs.DefaultFieldMapping()
+	if len(s.TimeFields) > 0 {
+		mapping.Time = s.TimeFields
+	}
+	if len(s.LevelFields) > 0 {
+		mapping.Level = s.LevelFields
+	}
+	if len(s.MessageFields) > 0 {
+		mapping.Message = s.MessageFields
+	}
+	return mapping
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/toyz/ssh-thing/logs"
+)
+
+// Problem is a single issue found in a config file. Line and Column are
+// 1-based and zero when the problem has no position in the file.
+//
+// Warnings are conditions that can only be checked against the machine the
+// app runs on, such as a missing key file. They don't stop the config from
+// loading; the affected server fails when it connects instead.
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Warning bool
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	b.WriteString(p.File)
+	if p.Line > 0 {
+		fmt.Fprintf(&b, ":%d", p.Line)
+		if p.Column > 0 {
+			fmt.Fprintf(&b, ":%d", p.Column)
+		}
+	}
+	b.WriteString(": ")
+	if p.Warning {
+		b.WriteString("warning: ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// ValidationError lists every problem found in a config file that stops it
+// from loading.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// decodeConfig loads the config at path and every file it includes,
+// resolves defaults and templates, and then checks the values. All problems
+// are returned together, except for syntax errors which stop loading.
+func decodeConfig(path string) (Config, []Problem) {
+	cfg, docs, problems, ok := decodeFiles(path)
+	if !ok {
+		return cfg, problems
+	}
+
+	problems = append(problems, cfg.load(docs, true)...)
+	problems = append(problems, cfg.validate(docs)...)
+	return cfg, problems
+}
+
+// load turns the decoded tables into the servers to connect to: inventory
+// servers are added when withInventory is set, defaults and templates are
+// applied, hosts are expanded and built-in defaults are filled in.
+func (c *Config) load(docs documents, withInventory bool) []Problem {
+	var problems []Problem
+	if withInventory {
+		problems = c.loadInventory(docs)
+	}
+	problems = append(problems, c.resolve(docs)...)
+	problems = append(problems, c.expandHosts(docs)...)
+
+	for i := range c.Servers {
+		if err := c.Servers[i].ApplyDefaults(); err != nil {
+			problems = append(problems, Problem{File: c.Path, Message: err.Error()})
+			break
+		}
+	}
+	return problems
+}
+
+// validate checks the values of a decoded config, using docs to find where
+// each problem is.
+func (c *Config) validate(docs documents) []Problem {
+	var problems []Problem
+	reportServer := func(index int, key string, format string, args ...any) {
+		server := c.Servers[index]
+		message := fmt.Sprintf(format, args...)
+		if origin, ok := server.Inherited[key]; ok {
+			message += " (from " + origin + ")"
+		}
+		problems = append(problems, docs.serverProblem(server, key, message))
+	}
+	warnServer := func(index int, key string, format string, args ...any) {
+		reportServer(index, key, format, args...)
+		problems[len(problems)-1].Warning = true
+	}
+
+	if len(c.Servers) == 0 {
+		problems = append(problems, Problem{File: c.Path, Message: "no servers configured"})
+	}
+
+	for i, rule := range c.Highlights {
+		if key, err := validateHighlight(rule); err != nil {
+			at := c.highlightOrigins[i]
+			problems = append(problems, docs.problem(at.file, "highlight", at.index, key, err.Error()))
+		}
+	}
+
+	names := make(map[string]int)
+	for i, server := range c.Servers {
+		label := server.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+			reportServer(i, "", "server %s has no name", label)
+		} else if first, ok := names[server.Name]; ok {
+			switch other := c.Servers[first]; {
+			case other.Source != "":
+				reportServer(i, "name", "server name %q is already used by %s", server.Name, other.Source)
+			case other.File != server.File:
+				line, _ := docs[other.File].position("servers", other.Table, "name")
+				reportServer(i, "name", "server name %q is already used in %s:%d", server.Name, other.File, line)
+			default:
+				line, _ := docs[other.File].position("servers", other.Table, "name")
+				reportServer(i, "name", "server name %q is already used on line %d", server.Name, line)
+			}
+		} else {
+			names[server.Name] = i
+		}
+
+		if server.Host == "" {
+			reportServer(i, "host", "server %s: host is required", label)
+		}
+
+		if server.Port < 0 || server.Port > 65535 {
+			reportServer(i, "port", "server %s: port %d is not between 1 and 65535", label, server.Port)
+		}
+
+		// Environment variables are resolved when connecting, so a path that
+		// refers to one that is not set is checked then instead.
+		checkFile := func(key, path, what string) {
+			resolved, err := Interpolate(path)
+			if err != nil {
+				return
+			}
+			if expanded, err := expandHome(resolved); err == nil {
+				resolved = expanded
+			}
+			if _, err := os.Stat(resolved); err != nil {
+				warnServer(i, key, "server %s: %s %s: %v", label, what, path, errors.Unwrap(err))
+			}
+		}
+
+		var passwords []string
+		for _, setting := range []setting{{"password", &server.Password}, {"password_command", &server.PasswordCommand}, {"password_file", &server.PasswordFile}} {
+			if *setting.value != "" {
+				passwords = append(passwords, setting.key)
+			}
+		}
+		if len(passwords) > 1 {
+			reportServer(i, passwords[1], "server %s: set only one of %s", label, strings.Join(passwords, ", "))
+		}
+
+		switch {
+		case server.PrivateKeyPath != "":
+			checkFile("private_key_path", server.PrivateKeyPath, "private key")
+		case len(passwords) == 0:
+			reportServer(i, "", "server %s: private_key_path, password, password_command or password_file is required", label)
+		}
+		if server.PasswordFile != "" {
+			checkFile("password_file", server.PasswordFile, "password file")
+		}
+
+		if _, err := logs.NewParser(server.Format, c.Structured.FieldMapping()); err != nil {
+			reportServer(i, "format", "server %s: %v", label, err)
+		}
+
+		for _, rule := range server.Highlights {
+			if _, err := validateHighlight(rule); err != nil {
+				reportServer(i, "", "server %s: %v", label, err)
+			}
+		}
+	}
+
+	return problems
+}
+
+// serverProblem reports a problem with server at key in its table. Servers
+// from an inventory have no table, so their problems name the inventory
+// instead.
+func (d documents) serverProblem(server SSHServer, key, message string) Problem {
+	if server.Source != "" {
+		return Problem{File: server.File, Message: server.Source + ": " + message}
+	}
+	return d.problem(server.File, "servers", server.Table, key, message)
+}
+
+// validateHighlight checks that a rule's pattern compiles and has the
+// capture group it colors, and returns the key of the rule that is wrong.
+func validateHighlight(rule HighlightRule) (string, error) {
+	pattern, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return "pattern", fmt.Errorf("invalid highlight rule %q: %w", rule.Name, err)
+	}
+	if rule.Group < 0 || rule.Group > pattern.NumSubexp() {
+		return "group", fmt.Errorf("invalid highlight rule %q: pattern has no capture group %d", rule.Name, rule.Group)
+	}
+	return "", nil
+}
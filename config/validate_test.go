@@ -0,0 +1,197 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes files, by their path relative to a temporary
+// directory, and returns the directory.
+func writeConfig(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// problemStrings formats problems with their files relative to dir.
+func problemStrings(dir string, problems []Problem) []string {
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = strings.ReplaceAll(p.String(), dir+string(filepath.Separator), "")
+	}
+	return lines
+}
+
+func checkProblems(t *testing.T, dir string, problems []Problem, want []string) {
+	t.Helper()
+	got := problemStrings(dir, problems)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidatePositions(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		input string
+		want  []string
+	}{
+		{
+			name: "valid",
+			file: "servers.toml",
+			input: `[[servers]]
+name = "web"
+host = "web.example.com"
+user = "deploy"
+password = "secret"
+`,
+		},
+		{
+			name: "syntax error",
+			file: "servers.toml",
+			input: `[[servers]]
+name = "web
+host = "web.example.com"
+`,
+			want: []string{"servers.toml:2:12: basic strings cannot have new lines"},
+		},
+		{
+			name: "unknown keys",
+			file: "servers.toml",
+			input: `[[servers]]
+name = "web"
+host = "web.example.com"
+user = "deploy"
+password = "secret"
+  privat_key_path = "~/.ssh/id_ed25519"
+
+[structured]
+fields = ["level"]
+`,
+			want: []string{
+				"servers.toml:6:3: unknown key servers.privat_key_path",
+				"servers.toml:9:1: unknown key structured.fields",
+			},
+		},
+		{
+			name: "wrong type",
+			file: "servers.toml",
+			input: `[[servers]]
+name = "web"
+host = "web.example.com"
+port = "22"
+`,
+			want: []string{"servers.toml:4:8: cannot decode TOML string into struct field config.SSHServer.Port of type int"},
+		},
+		{
+			name: "values",
+			file: "servers.toml",
+			input: `# Production
+[[servers]]
+name = "web"
+user = "deploy"
+password = "secret"
+port = 70000
+
+[[servers]]
+name = "web"
+host = "web2.example.com"
+password = "secret"
+password_file = "/etc/passwords/web"
+format = "xml"
+`,
+			want: []string{
+				"servers.toml:2:1: server web: host is required",
+				"servers.toml:6:1: server web: port 70000 is not between 1 and 65535",
+				`servers.toml:9:1: server name "web" is already used on line 3`,
+				"servers.toml:12:1: server web: set only one of password, password_file",
+				"servers.toml:12:1: warning: server web: password file /etc/passwords/web: no such file or directory",
+				`servers.toml:13:1: server web: unknown log format "xml"`,
+			},
+		},
+		{
+			name: "highlight rules",
+			file: "servers.toml",
+			input: `[[highlight]]
+name = "ids"
+pattern = 'id=(\d+)'
+group = 2
+
+[[highlight]]
+name = "broken"
+pattern = "(["
+
+[[servers]]
+name = "web"
+host = "web.example.com"
+password = "secret"
+`,
+			want: []string{
+				`servers.toml:4:1: invalid highlight rule "ids": pattern has no capture group 2`,
+				"servers.toml:8:1: invalid highlight rule \"broken\": error parsing regexp: missing closing ]: `[`",
+			},
+		},
+		{
+			name: "missing files are warnings",
+			file: "servers.toml",
+			input: `[[servers]]
+name = "web"
+host = "web.example.com"
+private_key_path = "/nonexistent/id_ed25519"
+`,
+			want: []string{"servers.toml:4:1: warning: server web: private key /nonexistent/id_ed25519: no such file or directory"},
+		},
+		{
+			name: "yaml",
+			file: "servers.yaml",
+			input: `servers:
+  - name: web
+    host: web.example.com
+    password: secret
+    prot: 22
+  - name: db
+    password: secret
+`,
+			want: []string{
+				"servers.yaml:5:5: unknown key servers.prot",
+				"servers.yaml:6:5: server db: host is required",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeConfig(t, map[string]string{tc.file: tc.input})
+			_, problems := decodeConfig(filepath.Join(dir, tc.file))
+			checkProblems(t, dir, problems, tc.want)
+		})
+	}
+}
+
+func TestLoadConfigWarnings(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"servers.toml": `[[servers]]
+name = "web"
+host = "web.example.com"
+private_key_path = "/nonexistent/id_ed25519"
+`})
+
+	cfg, err := LoadConfig(filepath.Join(dir, "servers.toml"))
+	if err != nil {
+		t.Fatalf("LoadConfig = %v, want only warnings", err)
+	}
+	if len(cfg.Servers) != 1 || len(cfg.Warnings) != 1 || !cfg.Warnings[0].Warning {
+		t.Errorf("servers = %d, warnings = %v", len(cfg.Servers), cfg.Warnings)
+	}
+}
@@ -0,0 +1,62 @@
+package logs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// combinedPattern matches the NCSA common and combined access log formats
+// used by nginx and Apache.
+var combinedPattern = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "([^"]*)" (\d{3}) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+func ParseCombined(line string) (*Entry, bool) {
+	m := combinedPattern.FindStringSubmatch(strings.TrimSpace(ansi.Strip(line)))
+	if m == nil {
+		return nil, false
+	}
+
+	entry := &Entry{
+		Time:    m[4],
+		Message: m[5],
+		Level:   LevelInfo,
+	}
+
+	fields := []Field{{Key: "remote_addr", Value: m[1]}}
+	if m[3] != "-" {
+		fields = append(fields, Field{Key: "user", Value: m[3]})
+	}
+
+	if parts := strings.Fields(m[5]); len(parts) == 3 {
+		fields = append(fields,
+			Field{Key: "method", Value: parts[0]},
+			Field{Key: "path", Value: parts[1]},
+			Field{Key: "protocol", Value: parts[2]},
+		)
+	}
+
+	fields = append(fields, Field{Key: "status", Value: m[6]})
+	if m[7] != "-" {
+		fields = append(fields, Field{Key: "bytes", Value: m[7]})
+	}
+	if m[8] != "" && m[8] != "-" {
+		fields = append(fields, Field{Key: "referer", Value: m[8]})
+	}
+	if m[9] != "" && m[9] != "-" {
+		fields = append(fields, Field{Key: "user_agent", Value: m[9]})
+	}
+	entry.Fields = fields
+
+	if status, err := strconv.Atoi(m[6]); err == nil {
+		switch {
+		case status >= 500:
+			entry.Level = LevelError
+		case status >= 400:
+			entry.Level = LevelWarn
+		}
+	}
+
+	return entry, true
+}
@@ -0,0 +1,74 @@
+package logs
+
+import "testing"
+
+func TestParseCombined(t *testing.T) {
+	tests := []parseCase{
+		{
+			name:    "nginx combined",
+			line:    `203.0.113.7 - - [10/Oct/2023:13:55:36 +0000] "GET /api/users?page=2 HTTP/1.1" 200 1534 "https://example.com/" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			time:    "10/Oct/2023:13:55:36 +0000",
+			level:   LevelInfo,
+			message: "GET /api/users?page=2 HTTP/1.1",
+			fields: map[string]string{
+				"remote_addr": "203.0.113.7",
+				"method":      "GET",
+				"path":        "/api/users?page=2",
+				"protocol":    "HTTP/1.1",
+				"status":      "200",
+				"bytes":       "1534",
+				"referer":     "https://example.com/",
+				"user_agent":  "Mozilla/5.0 (X11; Linux x86_64)",
+			},
+		},
+		{
+			name:    "apache server error",
+			line:    `192.168.1.20 - frank [10/Oct/2023:13:55:37 -0700] "POST /login HTTP/1.0" 503 - "-" "curl/8.4.0"`,
+			time:    "10/Oct/2023:13:55:37 -0700",
+			level:   LevelError,
+			message: "POST /login HTTP/1.0",
+			fields: map[string]string{
+				"user":       "frank",
+				"method":     "POST",
+				"status":     "503",
+				"user_agent": "curl/8.4.0",
+			},
+			status5xx: true,
+		},
+		{
+			name:    "common log client error",
+			line:    `10.0.0.1 - - [10/Oct/2023:13:55:38 +0000] "GET /missing HTTP/1.1" 404 162`,
+			time:    "10/Oct/2023:13:55:38 +0000",
+			level:   LevelWarn,
+			message: "GET /missing HTTP/1.1",
+			fields:  map[string]string{"path": "/missing", "status": "404", "bytes": "162"},
+		},
+		{
+			name:      "escaped quote in user agent",
+			line:      `10.0.0.2 - - [10/Oct/2023:13:55:39 +0000] "GET / HTTP/1.1" 500 0 "-" "agent \"x\""`,
+			time:      "10/Oct/2023:13:55:39 +0000",
+			level:     LevelError,
+			message:   "GET / HTTP/1.1",
+			fields:    map[string]string{"status": "500", "user_agent": `agent \"x\"`},
+			status5xx: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, ok := ParseCombined(tc.line)
+			checkEntry(t, tc, entry, ok)
+		})
+	}
+}
+
+func TestParseCombinedRejects(t *testing.T) {
+	for _, line := range []string{
+		"level=info msg=hello",
+		`127.0.0.1 - - [10/Oct/2023:13:55:36 +0000] "GET /" abc 12`,
+	} {
+		if _, ok := ParseCombined(line); ok {
+			t.Errorf("%q was parsed as an access log line", line)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var conditionOps = []string{">=", "<=", "!=", "!~", "=", "~", ">", "<"}
+
+// Condition compares one field of an Entry against a value, e.g.
+// "status>=500", "path~^/api" or "level>=warn". Numbers, levels and
+// timestamps are compared by value; everything else as strings.
+type Condition struct {
+	Field string
+	Op    string
+	Value string
+
+	pattern *regexp.Regexp
+	number  float64
+	isNum   bool
+	level   Level
+	time    time.Time
+	isTime  bool
+	// relative is set for values like "-15m" or "now", which are resolved
+	// against the current time on every match.
+	relative *time.Duration
+}
+
+func NewCondition(field, op, value string) (*Condition, error) {
+	c := &Condition{
+		Field: strings.TrimSpace(field),
+		Op:    op,
+		Value: strings.TrimSpace(value),
+	}
+	if c.Field == "" {
+		return nil, fmt.Errorf("missing field name")
+	}
+
+	switch op {
+	case "~", "!~":
+		pattern, err := regexp.Compile(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for %s: %w", c.Field, err)
+		}
+		c.pattern = pattern
+		return c, nil
+	case "=", "!=", ">", ">=", "<", "<=":
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+
+	if strings.EqualFold(c.Field, "level") {
+		level, ok := ParseLevel(c.Value)
+		if !ok {
+			return nil, fmt.Errorf("unknown level %q", c.Value)
+		}
+		c.level = level
+		return c, nil
+	}
+
+	if n, err := strconv.ParseFloat(c.Value, 64); err == nil {
+		c.number, c.isNum = n, true
+	} else if t, ok := ParseTime(c.Value); ok {
+		c.time, c.isTime = t, true
+	} else if d, ok := parseRelativeTime(c.Value); ok {
+		c.relative, c.isTime = &d, true
+	}
+
+	return c, nil
+}
+
+func (c *Condition) Match(entry *Entry) bool {
+	if strings.EqualFold(c.Field, "level") && c.pattern == nil {
+		return entry.Level != LevelNone && compare(int(entry.Level)-int(c.level), c.Op)
+	}
+
+	value, ok := entry.Get(c.Field)
+	if !ok {
+		return c.Op == "!=" || c.Op == "!~"
+	}
+
+	switch c.Op {
+	case "~":
+		return c.pattern.MatchString(value)
+	case "!~":
+		return !c.pattern.MatchString(value)
+	}
+
+	if c.isNum {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			switch {
+			case n < c.number:
+				return compare(-1, c.Op)
+			case n > c.number:
+				return compare(1, c.Op)
+			default:
+				return compare(0, c.Op)
+			}
+		}
+	}
+
+	if c.isTime {
+		if t, ok := ParseTime(value); ok {
+			ref := c.time
+			if c.relative != nil {
+				ref = time.Now().Add(*c.relative)
+			}
+			return compare(t.Compare(ref), c.Op)
+		}
+	}
+
+	if c.Op == "=" || c.Op == "!=" {
+		return compare(boolCmp(strings.EqualFold(value, c.Value)), c.Op)
+	}
+	return compare(strings.Compare(value, c.Value), c.Op)
+}
+
+func (c *Condition) String() string {
+	return c.Field + c.Op + c.Value
+}
+
+// parseRelativeTime accepts "now", "now-1h" and "-15m" style offsets.
+func parseRelativeTime(value string) (time.Duration, bool) {
+	value = strings.ToLower(value)
+	if value == "now" {
+		return 0, true
+	}
+
+	value = strings.TrimPrefix(value, "now")
+	if !strings.HasPrefix(value, "-") && !strings.HasPrefix(value, "+") {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+func boolCmp(equal bool) int {
+	if equal {
+		return 0
+	}
+	return 1
+}
+
+func compare(cmp int, op string) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
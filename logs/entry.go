@@ -0,0 +1,146 @@
+package logs
+
+import (
+	"strings"
+	"time"
+)
+
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Entry is a log line that was parsed into fields. Time, Level and Message
+// are extracted from the fields named by a FieldMapping; every other field is
+// kept in Fields in the order it appeared in the line.
+type Entry struct {
+	Time    string
+	Level   Level
+	Message string
+	Fields  []Field
+}
+
+// FieldMapping lists, in order of preference, which field names hold the
+// timestamp, level and message of an entry.
+type FieldMapping struct {
+	Time    []string
+	Level   []string
+	Message []string
+}
+
+func DefaultFieldMapping() FieldMapping {
+	return FieldMapping{
+		Time:    []string{"time", "ts", "timestamp", "@timestamp", "t"},
+		Level:   []string{"level", "lvl", "severity", "loglevel", "log.level"},
+		Message: []string{"msg", "message", "text", "event"},
+	}
+}
+
+func newEntry(fields []Field, mapping FieldMapping) *Entry {
+	entry := &Entry{}
+	used := make(map[string]bool)
+
+	if key, value, ok := firstField(fields, mapping.Time); ok {
+		entry.Time = value
+		used[key] = true
+	}
+	if key, value, ok := firstField(fields, mapping.Level); ok {
+		if level, known := ParseLevel(value); known {
+			entry.Level = level
+			used[key] = true
+		}
+	}
+	if key, value, ok := firstField(fields, mapping.Message); ok {
+		entry.Message = value
+		used[key] = true
+	}
+
+	for _, f := range fields {
+		if !used[f.Key] {
+			entry.Fields = append(entry.Fields, f)
+		}
+	}
+
+	return entry
+}
+
+func firstField(fields []Field, names []string) (string, string, bool) {
+	for _, name := range names {
+		for _, f := range fields {
+			if strings.EqualFold(f.Key, name) {
+				return f.Key, f.Value, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"02/Jan/2006:15:04:05 -0700",
+	time.Stamp,
+}
+
+// ParseTime parses the timestamp formats commonly found in logs, including
+// the access log and BSD syslog formats. Syslog timestamps carry no year, so
+// the current year is assumed.
+func ParseTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if layout == time.Stamp {
+			t = t.AddDate(time.Now().Year(), 0, 0)
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// ParseLevel maps common level spellings (e.g. "warning", "err", "crit") to
+// a Level.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace":
+		return LevelTrace, true
+	case "debug", "dbg":
+		return LevelDebug, true
+	case "info", "information", "notice":
+		return LevelInfo, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "error", "err":
+		return LevelError, true
+	case "fatal", "crit", "critical", "panic", "emerg", "alert":
+		return LevelFatal, true
+	}
+	return LevelNone, false
+}
+
+// Get returns the value of a field by name. The names "time", "level" and
+// "msg"/"message" refer to the mapped fields.
+func (e *Entry) Get(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "time":
+		return e.Time, e.Time != ""
+	case "level":
+		return e.Level.String(), e.Level != LevelNone
+	case "msg", "message":
+		return e.Message, e.Message != ""
+	}
+
+	for _, f := range e.Fields {
+		if strings.EqualFold(f.Key, name) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ParseJSON parses a line holding a single JSON object. Field order is
+// preserved and nested values are kept as compact JSON.
+func ParseJSON(line string, mapping FieldMapping) (*Entry, bool) {
+	raw, ok := jsonObject(line)
+	if !ok {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+
+	var fields []Field
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, false
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, false
+		}
+
+		fields = append(fields, Field{Key: key, Value: jsonValue(value)})
+	}
+
+	return newEntry(fields, mapping), true
+}
+
+// IndentJSON pretty-prints a JSON line, returning false if the line does not
+// hold a JSON object.
+func IndentJSON(line string) (string, bool) {
+	raw, ok := jsonObject(line)
+	if !ok {
+		return "", false
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(raw), "", "  "); err != nil {
+		return "", false
+	}
+	return out.String(), true
+}
+
+func jsonObject(line string) (string, bool) {
+	line = strings.TrimSpace(ansi.Strip(line))
+	if !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}") {
+		return "", false
+	}
+	return line, json.Valid([]byte(line))
+}
+
+func jsonValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var out bytes.Buffer
+	if err := json.Compact(&out, raw); err == nil {
+		return out.String()
+	}
+	return string(raw)
+}
@@ -0,0 +1,82 @@
+package logs
+
+import (
+	"regexp"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+type Level int
+
+const (
+	LevelNone Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// Levels lists every real severity from most to least severe, which is also
+// the order DetectLevel checks them in.
+var Levels = []Level{LevelFatal, LevelError, LevelWarn, LevelInfo, LevelDebug, LevelTrace}
+
+var (
+	InfoPattern      = regexp.MustCompile(`(?i)(\[?INFO\]?[:|\s]+)|(INFO\s*-\s*)|(INFO\s{2,})|\bINFO\b`)
+	ErrorPattern     = regexp.MustCompile(`(?i)(\[?ERROR\]?[:|\s]+)|(ERROR\s*-\s*)|(ERROR\s{2,})|\bERROR\b`)
+	WarnPattern      = regexp.MustCompile(`(?i)(\[?WARN(ING)?\]?[:|\s]+)|(\[?WARNING\]?[:|\s]+)|(WARN(ING)?\s*-\s*)|(WARN(ING)?\s{2,})|\bWARN(ING)?\b`)
+	DebugPattern     = regexp.MustCompile(`(?i)(\[?DEBUG\]?[:|\s]+)|(DEBUG\s*-\s*)|(DEBUG\s{2,})|\bDEBUG\b`)
+	TracePattern     = regexp.MustCompile(`(?i)(\[?TRACE\]?[:|\s]+)|(TRACE\s*-\s*)|(TRACE\s{2,})|\bTRACE\b`)
+	FatalPattern     = regexp.MustCompile(`(?i)(\[?FATAL\]?[:|\s]+)|(FATAL\s*-\s*)|\bFATAL\b`)
+	TimestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}([.,]\d+)?(Z|[+-]\d{2}:\d{2})`)
+)
+
+var levelPatterns = map[Level]*regexp.Regexp{
+	LevelFatal: FatalPattern,
+	LevelError: ErrorPattern,
+	LevelWarn:  WarnPattern,
+	LevelInfo:  InfoPattern,
+	LevelDebug: DebugPattern,
+	LevelTrace: TracePattern,
+}
+
+// DetectLevel classifies a line using the same patterns the colorizer
+// highlights. Lines without a recognisable level return LevelNone.
+func DetectLevel(line string) Level {
+	line = ansi.Strip(line)
+	for _, level := range Levels {
+		if levelPatterns[level].MatchString(line) {
+			return level
+		}
+	}
+	return LevelNone
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
+	default:
+		return "ALL"
+	}
+}
+
+// Next returns the following minimum level when cycling through levels,
+// wrapping back to LevelNone (show everything) after LevelFatal.
+func (l Level) Next() Level {
+	if l >= LevelFatal {
+		return LevelNone
+	}
+	return l + 1
+}
@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ParseLogfmt parses lines made entirely of key=value pairs, where values
+// may be double-quoted. Bare keys are allowed, but at least one pair must
+// have a value.
+func ParseLogfmt(line string, mapping FieldMapping) (*Entry, bool) {
+	line = strings.TrimSpace(ansi.Strip(line))
+
+	var fields []Field
+	hasValue := false
+
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, false
+		}
+
+		if i >= len(line) || line[i] == ' ' {
+			fields = append(fields, Field{Key: key})
+			continue
+		}
+		if line[i] != '=' {
+			return nil, false
+		}
+		i++
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			var b strings.Builder
+			i++
+			closed := false
+			for i < len(line) {
+				c := line[i]
+				if c == '\\' && i+1 < len(line) {
+					b.WriteByte(line[i+1])
+					i += 2
+					continue
+				}
+				if c == '"' {
+					closed = true
+					i++
+					break
+				}
+				b.WriteByte(c)
+				i++
+			}
+			if !closed || (i < len(line) && line[i] != ' ') {
+				return nil, false
+			}
+			value = b.String()
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' {
+				if line[i] == '"' || line[i] == '=' {
+					return nil, false
+				}
+				i++
+			}
+			value = line[start:i]
+		}
+
+		fields = append(fields, Field{Key: key, Value: value})
+		hasValue = true
+	}
+
+	if !hasValue {
+		return nil, false
+	}
+	return newEntry(fields, mapping), true
+}
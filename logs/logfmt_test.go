@@ -0,0 +1,65 @@
+package logs
+
+import "testing"
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []parseCase{
+		{
+			name:    "request",
+			line:    `time=2023-10-11T22:14:15Z level=error msg="upstream failed" method=GET path=/api/users status=502 duration=1.2s`,
+			time:    "2023-10-11T22:14:15Z",
+			level:   LevelError,
+			message: "upstream failed",
+			fields: map[string]string{
+				"method":   "GET",
+				"path":     "/api/users",
+				"status":   "502",
+				"duration": "1.2s",
+			},
+			status5xx: true,
+		},
+		{
+			name:    "escaped quotes",
+			line:    `ts=2023-10-11T22:14:16Z lvl=info msg="said \"hi\"" status=200`,
+			time:    "2023-10-11T22:14:16Z",
+			level:   LevelInfo,
+			message: `said "hi"`,
+			fields:  map[string]string{"status": "200"},
+		},
+		{
+			name:    "warning spelling and bare key",
+			line:    `level=warning msg=retrying attempt=3 cached`,
+			level:   LevelWarn,
+			message: "retrying",
+			fields:  map[string]string{"attempt": "3", "cached": ""},
+		},
+		{
+			name:      "unknown level",
+			line:      `level=verbose msg=hello status=500`,
+			message:   "hello",
+			fields:    map[string]string{"status": "500"},
+			status5xx: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, ok := ParseLogfmt(tc.line, DefaultFieldMapping())
+			checkEntry(t, tc, entry, ok)
+		})
+	}
+}
+
+func TestParseLogfmtRejects(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"just some words",
+		`msg="unterminated`,
+		`key=a"b`,
+		`=value`,
+	} {
+		if _, ok := ParseLogfmt(line, DefaultFieldMapping()); ok {
+			t.Errorf("%q was parsed as logfmt", line)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatAuto     = "auto"
+	FormatJSON     = "json"
+	FormatLogfmt   = "logfmt"
+	FormatCombined = "combined"
+	FormatSyslog   = "syslog"
+)
+
+// Parser turns a raw line into an Entry. Parse returns false when the line is
+// not in the parser's format.
+type Parser interface {
+	Parse(line string) (*Entry, bool)
+}
+
+type ParserFunc func(line string) (*Entry, bool)
+
+func (f ParserFunc) Parse(line string) (*Entry, bool) {
+	return f(line)
+}
+
+// NewParser returns the parser for a format name. An empty name selects
+// FormatAuto, which tries every format in turn.
+func NewParser(format string, mapping FieldMapping) (Parser, error) {
+	jsonParser := ParserFunc(func(line string) (*Entry, bool) { return ParseJSON(line, mapping) })
+	logfmtParser := ParserFunc(func(line string) (*Entry, bool) { return ParseLogfmt(line, mapping) })
+
+	switch strings.ToLower(format) {
+	case "", FormatAuto:
+		return ParserFunc(func(line string) (*Entry, bool) {
+			for _, p := range []Parser{jsonParser, ParserFunc(ParseCombined), ParserFunc(ParseSyslog), logfmtParser} {
+				if entry, ok := p.Parse(line); ok {
+					return entry, true
+				}
+			}
+			return nil, false
+		}), nil
+	case FormatJSON:
+		return jsonParser, nil
+	case FormatLogfmt:
+		return logfmtParser, nil
+	case FormatCombined, "nginx", "apache":
+		return ParserFunc(ParseCombined), nil
+	case FormatSyslog:
+		return ParserFunc(ParseSyslog), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// CachedParser remembers the result for recently parsed lines, since the
+// same buffered lines are parsed again every time a view is redrawn.
+type CachedParser struct {
+	parser  Parser
+	size    int
+	mu      sync.Mutex
+	current map[string]*Entry
+	prev    map[string]*Entry
+}
+
+func NewCachedParser(parser Parser, size int) *CachedParser {
+	return &CachedParser{
+		parser:  parser,
+		size:    size,
+		current: make(map[string]*Entry),
+	}
+}
+
+func (c *CachedParser) Parse(line string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.current[line]; ok {
+		return entry, entry != nil
+	}
+
+	entry, ok := c.prev[line]
+	if !ok {
+		entry, _ = c.parser.Parse(line)
+	}
+
+	if len(c.current) >= c.size {
+		c.prev = c.current
+		c.current = make(map[string]*Entry, c.size)
+	}
+	c.current[line] = entry
+
+	return entry, entry != nil
+}
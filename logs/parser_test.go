@@ -0,0 +1,85 @@
+package logs
+
+import "testing"
+
+// parseCase is a sample line and what parsing it should produce. fields
+// lists the extra fields the entry must have; status5xx is whether the
+// entry matches the condition "status>=500".
+type parseCase struct {
+	name      string
+	line      string
+	time      string
+	level     Level
+	message   string
+	fields    map[string]string
+	status5xx bool
+}
+
+func checkEntry(t *testing.T, tc parseCase, entry *Entry, ok bool) {
+	t.Helper()
+	if !ok {
+		t.Fatalf("line was not parsed: %q", tc.line)
+	}
+	if entry.Time != tc.time {
+		t.Errorf("time = %q, want %q", entry.Time, tc.time)
+	}
+	if entry.Level != tc.level {
+		t.Errorf("level = %s, want %s", entry.Level, tc.level)
+	}
+	if entry.Message != tc.message {
+		t.Errorf("message = %q, want %q", entry.Message, tc.message)
+	}
+	for key, want := range tc.fields {
+		if got, ok := entry.Get(key); !ok || got != want {
+			t.Errorf("field %s = %q (present %v), want %q", key, got, ok, want)
+		}
+	}
+
+	status, err := NewCondition("status", ">=", "500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := status.Match(entry); got != tc.status5xx {
+		t.Errorf("status>=500 = %v, want %v", got, tc.status5xx)
+	}
+}
+
+func TestAutoParser(t *testing.T) {
+	parser, err := NewParser(FormatAuto, DefaultFieldMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line  string
+		field string
+		want  string
+	}{
+		{`{"level":"error","msg":"boom","status":503}`, "status", "503"},
+		{`127.0.0.1 - - [10/Oct/2023:13:55:36 -0700] "GET / HTTP/1.1" 200 512 "-" "curl/8.0"`, "method", "GET"},
+		{`<34>1 2023-10-11T22:14:15.003Z host su - ID47 - failed`, "app", "su"},
+		{`Oct 11 22:14:15 host sshd[4721]: Accepted publickey`, "pid", "4721"},
+		{`level=info msg=started status=200`, "status", "200"},
+	}
+
+	for _, tc := range tests {
+		entry, ok := parser.Parse(tc.line)
+		if !ok {
+			t.Errorf("line was not parsed: %q", tc.line)
+			continue
+		}
+		if got, _ := entry.Get(tc.field); got != tc.want {
+			t.Errorf("%q: field %s = %q, want %q", tc.line, tc.field, got, tc.want)
+		}
+	}
+
+	if _, ok := parser.Parse("just some text"); ok {
+		t.Error("plain text was parsed")
+	}
+}
+
+func TestNewParserUnknownFormat(t *testing.T) {
+	if _, err := NewParser("csv", DefaultFieldMapping()); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,327 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled filter expression over parsed entries, e.g.
+//
+//	level>=warn AND (service~"auth" OR status>=500) AND NOT path~^/health
+//
+// Comparisons use the operators of Condition. A value of the form A..B with
+// "=" matches the inclusive range between A and B, which is handy for time
+// windows: time=2024-05-01T10:00..2024-05-01T11:00. Times may also be given
+// relative to now, as in time>-15m.
+type Query struct {
+	src  string
+	root queryNode
+}
+
+// QueryError reports where in the expression parsing failed. Pos is a
+// 1-based column.
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("col %d: %s", e.Pos, e.Msg)
+}
+
+type queryNode interface {
+	match(entry *Entry) bool
+}
+
+type andNode struct{ left, right queryNode }
+type orNode struct{ left, right queryNode }
+type notNode struct{ node queryNode }
+type condNode struct{ cond *Condition }
+
+func (n andNode) match(e *Entry) bool  { return n.left.match(e) && n.right.match(e) }
+func (n orNode) match(e *Entry) bool   { return n.left.match(e) || n.right.match(e) }
+func (n notNode) match(e *Entry) bool  { return !n.node.match(e) }
+func (n condNode) match(e *Entry) bool { return n.cond.Match(e) }
+
+func ParseQuery(src string) (*Query, error) {
+	tokens, err := lexQuery(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &QueryError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+
+	return &Query{src: strings.TrimSpace(src), root: root}, nil
+}
+
+func (q *Query) Match(entry *Entry) bool {
+	return q.root.match(entry)
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lexQuery(src string) ([]queryToken, error) {
+	var tokens []queryToken
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		pos := i + 1
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokLParen, text: "(", pos: pos})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokRParen, text: ")", pos: pos})
+			i++
+
+		case c == '"':
+			var b strings.Builder
+			i++
+			closed := false
+			for i < len(src) {
+				if src[i] == '\\' && i+1 < len(src) {
+					b.WriteByte(src[i+1])
+					i += 2
+					continue
+				}
+				if src[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, &QueryError{Pos: pos, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, queryToken{kind: tokString, text: b.String(), pos: pos})
+
+		case strings.ContainsRune("=!<>~&|", rune(c)):
+			op := string(c)
+			if i+1 < len(src) && isTwoCharOp(src[i:i+2]) {
+				op = src[i : i+2]
+			}
+			i += len(op)
+
+			switch op {
+			case "&&":
+				tokens = append(tokens, queryToken{kind: tokAnd, text: op, pos: pos})
+			case "||":
+				tokens = append(tokens, queryToken{kind: tokOr, text: op, pos: pos})
+			case "!":
+				tokens = append(tokens, queryToken{kind: tokNot, text: op, pos: pos})
+			default:
+				tokens = append(tokens, queryToken{kind: tokOp, text: op, pos: pos})
+			}
+
+		default:
+			start := i
+			for i < len(src) && !unicode.IsSpace(rune(src[i])) && !strings.ContainsRune("()\"=!<>~&|", rune(src[i])) {
+				i++
+			}
+			// Values may contain operator characters after the first one,
+			// e.g. relative times (-15m) or ranges (1..5); those are joined
+			// by the parser.
+			word := src[start:i]
+			switch strings.ToUpper(word) {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokAnd, text: word, pos: pos})
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokOr, text: word, pos: pos})
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokNot, text: word, pos: pos})
+			default:
+				tokens = append(tokens, queryToken{kind: tokWord, text: word, pos: pos})
+			}
+		}
+	}
+
+	return append(tokens, queryToken{kind: tokEOF, pos: len(src) + 1}), nil
+}
+
+func isTwoCharOp(s string) bool {
+	switch s {
+	case ">=", "<=", "!=", "!~", "&&", "||":
+		return true
+	}
+	return false
+}
+
+func isComparisonOp(s string) bool {
+	for _, op := range conditionOps {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &QueryError{Pos: closing.pos, Msg: "expected )"}
+		}
+		return node, nil
+
+	case tokWord:
+		return p.parseComparison(tok)
+
+	case tokEOF:
+		return nil, &QueryError{Pos: tok.pos, Msg: "expected a comparison such as status>=500"}
+	}
+
+	return nil, &QueryError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+func (p *queryParser) parseComparison(field queryToken) (queryNode, error) {
+	op := p.next()
+	if op.kind != tokOp || !isComparisonOp(op.text) {
+		return nil, &QueryError{Pos: op.pos, Msg: fmt.Sprintf("expected an operator after %q", field.text)}
+	}
+
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, &QueryError{Pos: value.pos, Msg: fmt.Sprintf("expected a value after %q", op.text)}
+	}
+
+	text := value.text
+	if value.kind == tokWord {
+		// Glue bare words that the lexer split on operator characters back
+		// together, as long as there is no whitespace between them.
+		end := value.pos - 1 + len(value.text)
+		for {
+			tok := p.peek()
+			if (tok.kind != tokWord && tok.kind != tokOp) || tok.pos-1 != end {
+				break
+			}
+			p.next()
+			text += tok.text
+			end += len(tok.text)
+		}
+	}
+
+	if op.text == "=" && value.kind == tokWord && strings.Contains(text, "..") {
+		lo, hi, _ := strings.Cut(text, "..")
+		low, err := NewCondition(field.text, ">=", lo)
+		if err != nil {
+			return nil, &QueryError{Pos: value.pos, Msg: err.Error()}
+		}
+		high, err := NewCondition(field.text, "<=", hi)
+		if err != nil {
+			return nil, &QueryError{Pos: value.pos, Msg: err.Error()}
+		}
+		return andNode{condNode{low}, condNode{high}}, nil
+	}
+
+	cond, err := NewCondition(field.text, op.text, text)
+	if err != nil {
+		return nil, &QueryError{Pos: value.pos, Msg: err.Error()}
+	}
+	return condNode{cond}, nil
+}
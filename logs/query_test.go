@@ -0,0 +1,137 @@
+package logs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryMatch(t *testing.T) {
+	authError := &Entry{
+		Time:    "2024-05-01T10:30:00Z",
+		Level:   LevelError,
+		Message: `login failed for "alice"`,
+		Fields: []Field{
+			{Key: "service", Value: "auth"},
+			{Key: "status", Value: "503"},
+			{Key: "path", Value: "/api/login"},
+			{Key: "user", Value: "alice"},
+		},
+	}
+	webHealth := &Entry{
+		Time:    "2024-05-01T12:00:00Z",
+		Level:   LevelInfo,
+		Message: "ok",
+		Fields: []Field{
+			{Key: "service", Value: "web"},
+			{Key: "status", Value: "200"},
+			{Key: "path", Value: "/health"},
+		},
+	}
+	recent := &Entry{
+		Time:  time.Now().Add(-5 * time.Minute).UTC().Format(time.RFC3339),
+		Level: LevelWarn,
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		entry *Entry
+		want  bool
+	}{
+		// AND binds tighter than OR, and NOT tighter than AND.
+		{"AND before OR", "level=error AND status=503 OR service=web", webHealth, true},
+		{"OR after AND", "service=web OR level=error AND status=503", webHealth, true},
+		{"NOT before AND", "NOT service=auth AND status=500", webHealth, false},
+		{"NOT applies to one comparison", "NOT service=auth AND service=web", webHealth, true},
+		{"double NOT", "NOT NOT service=web", webHealth, true},
+		{"parentheses", "(service=web OR level=error) AND status=503", webHealth, false},
+		{"nested parentheses", "((service=web) AND (status=200 OR status=201))", webHealth, true},
+		{"NOT parentheses", "NOT (service=web AND status=200)", webHealth, false},
+		{"symbol operators", "level=error && !path~^/health || service=web", authError, true},
+		{"lowercase keywords", "level=error and not service=web", authError, true},
+
+		// Values may be quoted, and unquoted comparisons need no spaces.
+		{"glued", "level=error", authError, true},
+		{"spaced", "level = error", authError, true},
+		{"glued two-char operator", "status>=500", authError, true},
+		{"quoted", `path="/api/login"`, authError, true},
+		{"quoted with spaces", `message~"login failed"`, authError, true},
+		{"quoted with escaped quote", `message="login failed for \"alice\""`, authError, true},
+		{"quoted keyword", `service="and"`, authError, false},
+		{"regexp", "path~^/api/", authError, true},
+		{"negated regexp", "path!~^/api/", authError, false},
+
+		// A..B is an inclusive range.
+		{"number range", "status=500..599", authError, true},
+		{"number range excludes", "status=500..599", webHealth, false},
+		{"range bounds are inclusive", "status=200..200", webHealth, true},
+		{"time range", "time=2024-05-01T10:00..2024-05-01T11:00", authError, true},
+		{"time range excludes", "time=2024-05-01T10:00..2024-05-01T11:00", webHealth, false},
+		{"time comparison", "time<2024-05-01T11:00", authError, true},
+		{"relative time", "time>-15m", recent, true},
+		{"relative time excludes", "time>-15m", authError, false},
+		{"now", "time<now", recent, true},
+		{"now offset", "time>now-1m", recent, false},
+
+		// Numbers compare by value, other values as strings.
+		{"number", "status>=1000", authError, false},
+		{"number less", "status<1000", webHealth, true},
+		{"number equal", "status=503.0", authError, true},
+		{"string", "user<bob", authError, true},
+		{"string greater", "user>=bob", authError, false},
+		{"string equality ignores case", "service=AUTH", authError, true},
+		{"level", "level>=warn", authError, true},
+		{"level below", "level>=warn", webHealth, false},
+		{"missing field", "user=alice", webHealth, false},
+		{"missing field not equal", "user!=alice", webHealth, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) = %v", tc.query, err)
+			}
+			if got := q.Match(tc.entry); got != tc.want {
+				t.Errorf("%q matched %v, want %v", tc.query, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{"", 1, "expected a comparison"},
+		{"status>=", 9, `expected a value after ">="`},
+		{"status 500", 8, `expected an operator after "status"`},
+		{"status AND level=info", 8, `expected an operator after "status"`},
+		{"(level=error", 13, "expected )"},
+		{"level=error)", 12, `unexpected ")"`},
+		{"level=error AND", 16, "expected a comparison"},
+		{"status>=500 OR OR level=info", 16, `unexpected "OR"`},
+		{`message="abc`, 9, "unterminated string"},
+		{"level=bogus", 7, `unknown level "bogus"`},
+		{"path~(", 6, `expected a value after "~"`},
+		{`path~"("`, 6, "invalid pattern for path"},
+		{"level=warn..bogus", 7, `unknown level "bogus"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := ParseQuery(tc.query)
+			var qerr *QueryError
+			if !errors.As(err, &qerr) {
+				t.Fatalf("ParseQuery(%q) = %v, want a QueryError", tc.query, err)
+			}
+			if qerr.Pos != tc.pos || !strings.Contains(qerr.Msg, tc.msg) {
+				t.Errorf("ParseQuery(%q) = %v, want col %d: %s", tc.query, err, tc.pos, tc.msg)
+			}
+		})
+	}
+}
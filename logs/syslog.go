@@ -0,0 +1,105 @@
+package logs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	// rfc5424Pattern: <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+	rfc5424Pattern = regexp.MustCompile(`^<(\d{1,3})>(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[(?:[^\]\\]|\\.)*\])+)(?: (.*))?$`)
+	// rfc3164Pattern: [<PRI>]Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+	rfc3164Pattern = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^\s\[:]+)(?:\[(\d+)\])?: ?(.*)$`)
+)
+
+// ParseSyslog parses RFC 5424 and RFC 3164 (BSD) syslog lines. The PRI part
+// is optional for RFC 3164 so plain /var/log/syslog files parse as well.
+func ParseSyslog(line string) (*Entry, bool) {
+	line = strings.TrimSpace(ansi.Strip(line))
+
+	if m := rfc5424Pattern.FindStringSubmatch(line); m != nil {
+		entry := &Entry{Time: nilValue(m[3]), Message: m[9]}
+		entry.Level = syslogLevel(m[1])
+		entry.Fields = appendNonNil(nil,
+			Field{Key: "facility", Value: syslogFacility(m[1])},
+			Field{Key: "host", Value: m[4]},
+			Field{Key: "app", Value: m[5]},
+			Field{Key: "pid", Value: m[6]},
+			Field{Key: "msgid", Value: m[7]},
+			Field{Key: "sd", Value: m[8]},
+		)
+		return entry, true
+	}
+
+	if m := rfc3164Pattern.FindStringSubmatch(line); m != nil {
+		entry := &Entry{Time: m[2], Message: m[6]}
+		fields := []Field{}
+		if m[1] != "" {
+			entry.Level = syslogLevel(m[1])
+			fields = append(fields, Field{Key: "facility", Value: syslogFacility(m[1])})
+		} else {
+			entry.Level = DetectLevel(m[6])
+		}
+		entry.Fields = appendNonNil(fields,
+			Field{Key: "host", Value: m[3]},
+			Field{Key: "app", Value: m[4]},
+			Field{Key: "pid", Value: m[5]},
+		)
+		return entry, true
+	}
+
+	return nil, false
+}
+
+func syslogLevel(pri string) Level {
+	n, err := strconv.Atoi(pri)
+	if err != nil {
+		return LevelNone
+	}
+
+	switch n % 8 {
+	case 0, 1, 2:
+		return LevelFatal
+	case 3:
+		return LevelError
+	case 4:
+		return LevelWarn
+	case 5, 6:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+func syslogFacility(pri string) string {
+	n, err := strconv.Atoi(pri)
+	if err != nil || n/8 >= len(syslogFacilities) {
+		return ""
+	}
+	return syslogFacilities[n/8]
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func appendNonNil(fields []Field, values ...Field) []Field {
+	for _, f := range values {
+		if f.Value != "" && f.Value != "-" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
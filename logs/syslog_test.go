@@ -0,0 +1,81 @@
+package logs
+
+import "testing"
+
+func TestParseSyslog(t *testing.T) {
+	tests := []parseCase{
+		{
+			name:    "rfc5424",
+			line:    `<165>1 2023-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event log entry`,
+			time:    "2023-10-11T22:14:15.003Z",
+			level:   LevelInfo,
+			message: "An application event log entry",
+			fields: map[string]string{
+				"facility": "local4",
+				"host":     "mymachine.example.com",
+				"app":      "evntslog",
+				"msgid":    "ID47",
+				"sd":       `[exampleSDID@32473 iut="3" eventSource="Application"]`,
+			},
+		},
+		{
+			name:    "rfc5424 critical without structured data",
+			line:    `<34>1 2023-10-11T22:14:15.003Z mymachine su 4721 - - 'su root' failed for lonvick on /dev/pts/8`,
+			time:    "2023-10-11T22:14:15.003Z",
+			level:   LevelFatal,
+			message: "'su root' failed for lonvick on /dev/pts/8",
+			fields: map[string]string{
+				"facility": "auth",
+				"host":     "mymachine",
+				"app":      "su",
+				"pid":      "4721",
+			},
+		},
+		{
+			name:    "rfc3164 with priority",
+			line:    `<11>Oct 11 22:14:15 web01 nginx[812]: upstream timed out, status=504`,
+			time:    "Oct 11 22:14:15",
+			level:   LevelError,
+			message: "upstream timed out, status=504",
+			fields: map[string]string{
+				"facility": "user",
+				"host":     "web01",
+				"app":      "nginx",
+				"pid":      "812",
+			},
+		},
+		{
+			name:    "rfc3164 file line",
+			line:    `Oct  3 04:05:06 db01 postgres[2231]: WARNING: checkpoints are occurring too frequently`,
+			time:    "Oct  3 04:05:06",
+			level:   LevelWarn,
+			message: "WARNING: checkpoints are occurring too frequently",
+			fields:  map[string]string{"host": "db01", "app": "postgres", "pid": "2231"},
+		},
+		{
+			name:    "rfc3164 without pid",
+			line:    `Oct 11 22:14:15 gateway kernel: eth0 link up`,
+			time:    "Oct 11 22:14:15",
+			message: "eth0 link up",
+			fields:  map[string]string{"host": "gateway", "app": "kernel"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, ok := ParseSyslog(tc.line)
+			checkEntry(t, tc, entry, ok)
+		})
+	}
+}
+
+func TestParseSyslogRejects(t *testing.T) {
+	for _, line := range []string{
+		"level=info msg=hello",
+		"<34>1 2023-10-11T22:14:15.003Z too few fields",
+	} {
+		if _, ok := ParseSyslog(line); ok {
+			t.Errorf("%q was parsed as syslog", line)
+		}
+	}
+}
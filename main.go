@@ -11,16 +11,23 @@ import (
 )
 
 func main() {
-	keyBindsDefaultPath := os.Getenv("HOME") + "/.config/ssh-thing/keybinds.toml"
-	if util.IsWindows() {
-		keyBindsDefaultPath = os.Getenv("APPDATA") + "\\ssh-thing\\keybinds.toml"
-	}
-
-	serverConfigPath := flag.String("servers", "", "Path to the servers configuration file (default: ./servers.toml or ./config.toml)")
-	keybindsPath := flag.String("keybinds", "", fmt.Sprint("Path to the keybinds configuration file (default: ", keyBindsDefaultPath, ")"))
+	serverConfigPath := flag.String("servers", "", "Path to the servers configuration file or a directory of them (default: servers.toml or config.toml in the config search path)")
+	keybindsPath := flag.String("keybinds", "", "Path to the keybinds configuration file (default: keybinds.toml in the config search path)")
+	printConfigPaths := flag.Bool("print-config-paths", false, "Print the config search path and the files that are loaded, then exit")
 
 	flag.Parse()
 
+	if *printConfigPaths {
+		os.Exit(printPaths(*serverConfigPath, *keybindsPath))
+	}
+
+	switch flag.Arg(0) {
+	case "config":
+		os.Exit(runConfigCommand(flag.Args()[1:], *serverConfigPath, *keybindsPath))
+	case "vault":
+		os.Exit(runVaultCommand(flag.Args()[1:]))
+	}
+
 	if err := util.EnableVirtualTerminalProcessing(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to enable ANSI terminal support: %v\n", err)
 	}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/toyz/ssh-thing/config"
@@ -20,9 +21,72 @@ type Client struct {
 	stdin       io.WriteCloser
 	isLastCmd   bool
 	initialized bool
+
+	// done is closed when the client is closed, to stop whatever is reading
+	// its output.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// secrets are the resolved values removed from error messages.
+	secrets []string
+}
+
+// redactedError hides secrets in the message of the error it wraps.
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string { return e.message }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redact replaces every secret in err's message, so a password can't end up
+// in a tab.
+func redact(err error, secrets []string) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	for _, secret := range secrets {
+		if secret != "" {
+			message = strings.ReplaceAll(message, secret, "********")
+		}
+	}
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, message: message}
+}
+
+// fail reports an error on ErrChan with the client's secrets removed.
+func (c *Client) fail(err error) {
+	select {
+	case c.ErrChan <- redact(err, c.secrets):
+	case <-c.done:
+	}
+}
+
+// send passes output on to OutputChan. Once the client is closed nothing
+// reads it any more, so output is dropped instead of blocking.
+func (c *Client) send(output string) {
+	select {
+	case c.OutputChan <- output:
+	case <-c.done:
+	}
 }
 
-func NewClient(sshConfig *config.SSHServer) (*Client, error) {
+// NewClient connects to a server. Environment variables, password
+// references and vault secrets in its settings are resolved here, just
+// before connecting, and the resolved settings are kept as the client's
+// Config.
+func NewClient(server *config.SSHServer, store config.SecretStore) (*Client, error) {
+	resolved, err := server.Resolve(store)
+	if err != nil {
+		return nil, redact(err, []string{server.Password, server.PrivateKeyPassphrase})
+	}
+	sshConfig := &resolved
+	secrets := []string{resolved.Password, resolved.PrivateKeyPassphrase}
+
 	var authMethod ssh.AuthMethod
 
 	if sshConfig.PrivateKeyPath != "" {
@@ -31,9 +95,14 @@ func NewClient(sshConfig *config.SSHServer) (*Client, error) {
 			return nil, fmt.Errorf("unable to read private key: %w", err)
 		}
 
-		signer, err := ssh.ParsePrivateKey(key)
+		var signer ssh.Signer
+		if sshConfig.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(sshConfig.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("unable to parse private key: %w", err)
+			return nil, redact(fmt.Errorf("unable to parse private key: %w", err), secrets)
 		}
 
 		authMethod = ssh.PublicKeys(signer)
@@ -55,7 +124,7 @@ func NewClient(sshConfig *config.SSHServer) (*Client, error) {
 	addr := fmt.Sprintf("%s:%d", sshConfig.Host, sshConfig.Port)
 	client, err := ssh.Dial("tcp", addr, config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial: %w", err)
+		return nil, redact(fmt.Errorf("failed to dial: %w", err), secrets)
 	}
 
 	return &Client{
@@ -63,7 +132,9 @@ func NewClient(sshConfig *config.SSHServer) (*Client, error) {
 		SSHClient:  client,
 		OutputChan: make(chan string),
 		ErrChan:    make(chan error),
+		done:       make(chan struct{}),
 		isLastCmd:  false,
+		secrets:    secrets,
 	}, nil
 }
 
@@ -124,7 +195,7 @@ func (c *Client) initSession() error {
 
 	c.isLastCmd = false
 	if _, err := c.stdin.Write([]byte("clear\n")); err != nil {
-		c.ErrChan <- fmt.Errorf("warning: failed to clear terminal: %w", err)
+		c.fail(fmt.Errorf("warning: failed to clear terminal: %w", err))
 	}
 
 	time.Sleep(300 * time.Millisecond)
@@ -136,7 +207,7 @@ func (c *Client) initSession() error {
 func (c *Client) RunCommand(command string) {
 	go func() {
 		if err := c.initSession(); err != nil {
-			c.ErrChan <- err
+			c.fail(err)
 			return
 		}
 
@@ -145,7 +216,7 @@ func (c *Client) RunCommand(command string) {
 		}
 
 		if _, err := c.stdin.Write([]byte(command)); err != nil {
-			c.ErrChan <- fmt.Errorf("failed to send command: %w", err)
+			c.fail(fmt.Errorf("failed to send command: %w", err))
 
 			c.Close()
 			c.session = nil
@@ -161,7 +232,7 @@ func (c *Client) RunCommands(commands []string) {
 
 	go func() {
 		if err := c.initSession(); err != nil {
-			c.ErrChan <- err
+			c.fail(err)
 			return
 		}
 
@@ -183,7 +254,7 @@ func (c *Client) RunCommands(commands []string) {
 			}
 
 			if _, err := c.stdin.Write([]byte(cmd)); err != nil {
-				c.ErrChan <- fmt.Errorf("failed to send command: %w", err)
+				c.fail(fmt.Errorf("failed to send command: %w", err))
 
 				c.Close()
 				c.session = nil
@@ -193,7 +264,35 @@ func (c *Client) RunCommands(commands []string) {
 	}()
 }
 
+// Exec runs a command in a session of its own, alongside the shell running
+// the configured commands, and sends its combined output to OutputChan.
+func (c *Client) Exec(command string) {
+	go func() {
+		session, err := c.SSHClient.NewSession()
+		if err != nil {
+			c.fail(fmt.Errorf("failed to create session: %w", err))
+			return
+		}
+		defer session.Close()
+
+		output, err := session.CombinedOutput(command)
+		if len(output) > 0 {
+			c.send(string(output))
+		}
+		if err != nil {
+			c.fail(fmt.Errorf("command %q failed: %w", command, err))
+		}
+	}()
+}
+
+// Done returns a channel that is closed when the client is closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
 func (c *Client) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+
 	if c.session != nil {
 		c.session.Close()
 		c.session = nil
@@ -211,13 +310,13 @@ func (c *Client) streamOutput(r io.Reader) {
 		n, err := r.Read(buf)
 		if err != nil {
 			if err != io.EOF {
-				c.ErrChan <- fmt.Errorf("read error: %w", err)
+				c.fail(fmt.Errorf("read error: %w", err))
 			}
 			break
 		}
 		if n > 0 {
 			if c.isLastCmd {
-				c.OutputChan <- string(buf[:n])
+				c.send(string(buf[:n]))
 			}
 		}
 	}
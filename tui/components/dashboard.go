@@ -0,0 +1,177 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	DashboardCardWidth  = 36
+	DashboardCardHeight = 9
+)
+
+var (
+	DashboardCardStyle = lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("#44475a")). // Dracula Current Line
+				Padding(0, 1)
+
+	DashboardSelectedCardStyle = DashboardCardStyle.
+					BorderForeground(lipgloss.Color("#bd93f9")) // Dracula Purple
+
+	DashboardLabelStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#6272a4")) // Dracula Comment
+)
+
+// DashboardCard is the summary of one server shown on the dashboard.
+type DashboardCard struct {
+	Name   string
+	Host   string
+	Status string
+	Stats  TabStatsSnapshot
+}
+
+// Dashboard renders a grid of server cards. X and Y are the screen position it
+// was last rendered at, used for mouse handling.
+type Dashboard struct {
+	X, Y   int
+	Width  int
+	Height int
+}
+
+func NewDashboard() *Dashboard {
+	return &Dashboard{}
+}
+
+// Columns is the number of cards shown per row.
+func (d *Dashboard) Columns() int {
+	if cols := d.Width / DashboardCardWidth; cols > 0 {
+		return cols
+	}
+	return 1
+}
+
+func (d *Dashboard) rows() int {
+	if rows := d.Height / DashboardCardHeight; rows > 0 {
+		return rows
+	}
+	return 1
+}
+
+// firstRow is the first row of cards shown so that the selected card is
+// visible.
+func (d *Dashboard) firstRow(selected int) int {
+	row := selected / d.Columns()
+	if first := row - d.rows() + 1; first > 0 {
+		return first
+	}
+	return 0
+}
+
+// CardAt returns the index of the card at a position relative to the top left
+// of the dashboard.
+func (d *Dashboard) CardAt(x, y, selected, count int) (int, bool) {
+	col, row := x/DashboardCardWidth, y/DashboardCardHeight
+	if x < 0 || y < 0 || col >= d.Columns() || row >= d.rows() {
+		return 0, false
+	}
+
+	index := (d.firstRow(selected)+row)*d.Columns() + col
+	if index >= count {
+		return 0, false
+	}
+	return index, true
+}
+
+func (d *Dashboard) View(cards []DashboardCard, selected int) string {
+	if len(cards) == 0 {
+		return lipgloss.NewStyle().Width(d.Width).Height(d.Height).Render("No servers configured")
+	}
+
+	cols := d.Columns()
+	first := d.firstRow(selected) * cols
+	last := first + d.rows()*cols
+	if last > len(cards) {
+		last = len(cards)
+	}
+
+	var rows []string
+	for start := first; start < last; start += cols {
+		end := start + cols
+		if end > last {
+			end = last
+		}
+
+		var row []string
+		for i := start; i < end; i++ {
+			row = append(row, d.card(cards[i], i == selected))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	}
+
+	return lipgloss.NewStyle().
+		Width(d.Width).MaxWidth(d.Width).
+		Height(d.Height).MaxHeight(d.Height).
+		Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
+
+func (d *Dashboard) card(card DashboardCard, selected bool) string {
+	style := DashboardCardStyle
+	if selected {
+		style = DashboardSelectedCardStyle
+	}
+
+	// Border and padding take two columns each side.
+	inner := DashboardCardWidth - 4
+	now := time.Now()
+
+	uptime, idle := "-", "-"
+	if !card.Stats.ConnectedAt.IsZero() {
+		uptime = FormatDuration(now.Sub(card.Stats.ConnectedAt))
+	}
+	if !card.Stats.LastOutput.IsZero() {
+		idle = FormatDuration(now.Sub(card.Stats.LastOutput))
+	}
+
+	errors := "0"
+	if card.Stats.Errors > 0 {
+		errors = ErrorStyle.Render(fmt.Sprint(card.Stats.Errors))
+	}
+
+	field := func(label, value string) string {
+		return DashboardLabelStyle.Render(fmt.Sprintf("%-8s", label)) + value
+	}
+
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render(ansi.Truncate(card.Name, inner, "…")),
+		DashboardLabelStyle.Render(ansi.Truncate(card.Host, inner, "…")),
+		field("Status", lipgloss.NewStyle().Foreground(StatusColor(card.Status)).Render(card.Status)) +
+			DashboardLabelStyle.Render("  up ") + uptime,
+		field("Rate", fmt.Sprintf("%.1f lines/s", card.Stats.LinesPerSecond)),
+		field("Errors", errors) +
+			DashboardLabelStyle.Render("  idle ") + idle,
+		ansi.Truncate(strings.TrimSpace(ansi.Strip(card.Stats.LastLine)), inner, "…"),
+	}
+
+	return style.
+		Width(DashboardCardWidth - 2).
+		Height(DashboardCardHeight - 2).
+		Render(strings.Join(lines, "\n"))
+}
+
+// FormatDuration formats a duration compactly, e.g. "42s", "5m12s" or "3h04m".
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
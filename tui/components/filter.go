@@ -0,0 +1,81 @@
+package components
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// LineFilter decides whether a buffered line is shown in a ScrollView. Filters
+// only affect the view; the underlying ScrollBuffer always keeps every line.
+type LineFilter interface {
+	Match(line string) bool
+	String() string
+}
+
+type RegexFilter struct {
+	Pattern *regexp.Regexp
+	Exclude bool
+}
+
+func NewRegexFilter(expr string, exclude bool) (*RegexFilter, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter pattern: %w", err)
+	}
+
+	return &RegexFilter{
+		Pattern: pattern,
+		Exclude: exclude,
+	}, nil
+}
+
+func (f *RegexFilter) Match(line string) bool {
+	return f.Pattern.MatchString(ansi.Strip(line)) != f.Exclude
+}
+
+func (f *RegexFilter) String() string {
+	if f.Exclude {
+		return "!/" + f.Pattern.String() + "/"
+	}
+	return "/" + f.Pattern.String() + "/"
+}
+
+func matchAll(line string, filters []LineFilter) bool {
+	for _, f := range filters {
+		if !f.Match(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchFilters evaluates the filters against every line. Results are cached
+// per line until the filters change, so only newly arrived lines have to be
+// evaluated on each update.
+func (s *ScrollView) matchFilters(lines []string) []bool {
+	matches := make([]bool, len(lines))
+	if len(s.filters) == 0 {
+		for i := range matches {
+			matches[i] = true
+		}
+		return matches
+	}
+
+	cache := make(map[string]bool, len(lines))
+	for i, line := range lines {
+		match, ok := cache[line]
+		if !ok {
+			match, ok = s.matchCache[line]
+			if !ok {
+				match = matchAll(line, s.filters)
+			}
+			cache[line] = match
+		}
+		matches[i] = match
+	}
+
+	s.matchCache = cache
+	return matches
+}
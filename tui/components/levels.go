@@ -0,0 +1,83 @@
+package components
+
+import (
+	"github.com/toyz/ssh-thing/logs"
+)
+
+// SetMinLevel hides every classified line below level. Lines without a
+// detectable level (stack traces, continuation lines) are always shown.
+func (s *ScrollView) SetMinLevel(level logs.Level) {
+	s.minLevel = level
+}
+
+func (s *ScrollView) MinLevel() logs.Level {
+	return s.minLevel
+}
+
+// SetClassifier replaces logs.DetectLevel as the way lines are assigned a
+// level, e.g. to use the level field of parsed log lines.
+func (s *ScrollView) SetClassifier(classifier func(string) logs.Level) {
+	s.classifier = classifier
+	s.levelCache = nil
+}
+
+// LevelCounts returns how many buffered lines were classified at each level
+// during the last content update, regardless of filters.
+func (s *ScrollView) LevelCounts() map[logs.Level]int {
+	return s.levelCounts
+}
+
+// classify detects the level of each line, reusing results from the previous
+// update so unchanged lines are not matched against every pattern again.
+func (s *ScrollView) classify(lines []string) []logs.Level {
+	cache := make(map[string]logs.Level, len(lines))
+	counts := make(map[logs.Level]int)
+	levels := make([]logs.Level, len(lines))
+
+	for i, line := range lines {
+		level, ok := cache[line]
+		if !ok {
+			level, ok = s.levelCache[line]
+			if !ok {
+				if s.classifier != nil {
+					level = s.classifier(line)
+				} else {
+					level = logs.DetectLevel(line)
+				}
+			}
+			cache[line] = level
+		}
+
+		levels[i] = level
+		counts[level]++
+	}
+
+	s.levelCache = cache
+	s.levelCounts = counts
+	return levels
+}
+
+// JumpToLevel scrolls to the next (or previous) visible line at or above
+// level, relative to the top of the viewport. It reports whether such a line
+// was found.
+func (s *ScrollView) JumpToLevel(level logs.Level, forward bool) bool {
+	current := s.viewport.YOffset
+
+	if forward {
+		for i, l := range s.lineLevels {
+			if l >= level && s.lineRows[i] > current {
+				s.viewport.SetYOffset(s.lineRows[i])
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := len(s.lineLevels) - 1; i >= 0; i-- {
+		if s.lineLevels[i] >= level && s.lineRows[i] < current {
+			s.viewport.SetYOffset(s.lineRows[i])
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package components
 
 import (
 	"strings"
+	"sync"
 )
 
 const (
@@ -9,8 +10,10 @@ const (
 )
 
 type ScrollBuffer struct {
+	mu       sync.RWMutex
 	lines    []string
 	maxLines int
+	dropped  int
 }
 
 func NewScrollBuffer(maxLines int) *ScrollBuffer {
@@ -24,6 +27,9 @@ func NewScrollBuffer(maxLines int) *ScrollBuffer {
 }
 
 func (s *ScrollBuffer) Append(content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	newLines := strings.Split(content, "\n")
 
 	if len(s.lines) > 0 && !strings.HasSuffix(s.lines[len(s.lines)-1], "\n") && len(newLines) > 0 {
@@ -34,18 +40,77 @@ func (s *ScrollBuffer) Append(content string) {
 	s.lines = append(s.lines, newLines...)
 
 	if len(s.lines) > s.maxLines {
+		s.dropped += len(s.lines) - s.maxLines
 		s.lines = s.lines[len(s.lines)-s.maxLines:]
 	}
 }
 
 func (s *ScrollBuffer) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropped += len(s.lines)
 	s.lines = make([]string, 0, s.maxLines)
 }
 
+// AppendLines adds complete lines, e.g. for views that are assembled from
+// other buffers rather than streamed into.
+func (s *ScrollBuffer) AppendLines(lines []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lines = append(s.lines, lines...)
+
+	if len(s.lines) > s.maxLines {
+		s.dropped += len(s.lines) - s.maxLines
+		s.lines = s.lines[len(s.lines)-s.maxLines:]
+	}
+}
+
+// SetLines replaces the buffered lines, e.g. for views that are assembled
+// from other buffers rather than appended to. The new lines get new sequence
+// numbers, as they may not be the lines that were at the same positions.
+func (s *ScrollBuffer) SetLines(lines []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropped += len(s.lines)
+	if len(lines) > s.maxLines {
+		s.dropped += len(lines) - s.maxLines
+		lines = lines[len(lines)-s.maxLines:]
+	}
+	s.lines = append(make([]string, 0, s.maxLines), lines...)
+}
+
 func (s *ScrollBuffer) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	return strings.Join(s.lines, "\n")
 }
 
+// Lines returns a snapshot of the buffered lines that is safe to use while
+// new output keeps arriving.
+func (s *ScrollBuffer) Lines() []string {
+	lines, _ := s.Snapshot()
+	return lines
+}
+
+// Snapshot returns the buffered lines together with the sequence number of
+// the first one. Sequence numbers keep identifying the same line after older
+// lines have been trimmed from the buffer.
+func (s *ScrollBuffer) Snapshot() ([]string, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lines := make([]string, len(s.lines))
+	copy(lines, s.lines)
+	return lines, s.dropped
+}
+
 func (s *ScrollBuffer) LineCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	return len(s.lines)
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/toyz/ssh-thing/logs"
 )
 
 type ScrollView struct {
@@ -16,8 +17,27 @@ type ScrollView struct {
 	height       int
 	customBorder lipgloss.Border
 	hasBorder    bool
+	filters      []LineFilter
+	matchCache   map[string]bool
+	visibleLines int
+	colorizer    func(string) string
+	minLevel     logs.Level
+	classifier   func(string) logs.Level
+	levelCache   map[string]logs.Level
+	levelCounts  map[logs.Level]int
+	lineLevels   []logs.Level
+	lineRows     []int
+	lineIDs      []int
+	formatter    LineFormatter
+	expanded     map[int]bool
 }
 
+// LineFormatter renders a single buffered line in place of the colorizer.
+// It returns false to leave the line to the colorizer, e.g. when the line is
+// not in the format it understands. expanded reports whether the user asked
+// for the detailed rendering of this line.
+type LineFormatter func(line string, expanded bool) (string, bool)
+
 func NewScrollView() *ScrollView {
 	vp := viewport.New(0, 0)
 	vp.Style = ViewportStyle
@@ -70,10 +90,43 @@ func (s *ScrollView) Append(content string) {
 	s.buffer.Append(content)
 }
 
+func (s *ScrollView) AppendLines(lines []string) {
+	s.buffer.AppendLines(lines)
+}
+
+func (s *ScrollView) SetLines(lines []string) {
+	s.buffer.SetLines(lines)
+}
+
 func (s *ScrollView) Clear() {
 	s.buffer.Clear()
 	s.viewport.SetContent("")
 	s.userScrolled = false
+	s.visibleLines = 0
+}
+
+func (s *ScrollView) AddFilter(filter LineFilter) {
+	s.filters = append(s.filters, filter)
+	s.matchCache = nil
+}
+
+func (s *ScrollView) ClearFilters() {
+	s.filters = nil
+	s.matchCache = nil
+}
+
+func (s *ScrollView) Filters() []LineFilter {
+	return s.filters
+}
+
+func (s *ScrollView) IsFiltered() bool {
+	return len(s.filters) > 0
+}
+
+// VisibleLineCount returns the number of buffered lines that passed the
+// filters during the last content update.
+func (s *ScrollView) VisibleLineCount() int {
+	return s.visibleLines
 }
 
 func (s *ScrollView) SetSize(width, height int) {
@@ -108,76 +161,149 @@ func (s *ScrollView) LineCount() int {
 }
 
 func (s *ScrollView) IsScrollable() bool {
-	return s.viewport.Height < s.buffer.LineCount()
+	return s.viewport.Height < s.viewport.TotalLineCount()
 }
 
 func (s *ScrollView) ToggleWordWrap() {
 	s.wordWrap = !s.wordWrap
-	content := s.buffer.String()
-	if s.wordWrap {
-		content = s.wrapContent(content)
-	}
-	s.viewport.SetContent(content)
+	s.UpdateContent(s.colorizer)
 }
 
 func (s *ScrollView) IsWordWrapped() bool {
 	return s.wordWrap
 }
 
-func (s *ScrollView) wrapContent(content string) string {
+func (s *ScrollView) wrapWidth() int {
 	if s.width <= 0 {
-		return content
+		return 0
 	}
 
 	effectiveWidth := s.width - 4
 
 	if effectiveWidth <= 10 {
-		return content
+		return 0
+	}
+
+	return effectiveWidth
+}
+
+func wrapLine(line string, effectiveWidth int) []string {
+	if effectiveWidth <= 0 || len(line) <= effectiveWidth {
+		return []string{line}
 	}
 
-	lines := strings.Split(content, "\n")
 	var wrappedLines []string
+	currentPos := 0
+	for currentPos < len(line) {
+		endPos := currentPos + effectiveWidth
 
-	for _, line := range lines {
-		if len(line) <= effectiveWidth {
-			wrappedLines = append(wrappedLines, line)
-			continue
+		if endPos >= len(line) {
+			wrappedLines = append(wrappedLines, line[currentPos:])
+			break
 		}
 
-		currentPos := 0
-		for currentPos < len(line) {
-			endPos := currentPos + effectiveWidth
+		lastSpace := strings.LastIndex(line[currentPos:endPos], " ")
 
-			if endPos >= len(line) {
-				wrappedLines = append(wrappedLines, line[currentPos:])
-				break
-			}
+		if lastSpace != -1 {
+			wrappedLines = append(wrappedLines, line[currentPos:currentPos+lastSpace])
+			currentPos += lastSpace + 1
+		} else {
+			wrappedLines = append(wrappedLines, line[currentPos:endPos])
+			currentPos = endPos
+		}
+	}
+
+	return wrappedLines
+}
+
+func (s *ScrollView) UpdateContent(colorizer func(string) string) {
+	s.colorizer = colorizer
 
-			lastSpace := strings.LastIndex(line[currentPos:endPos], " ")
+	all, first := s.buffer.Snapshot()
+	levels := s.classify(all)
 
-			if lastSpace != -1 {
-				wrappedLines = append(wrappedLines, line[currentPos:currentPos+lastSpace])
-				currentPos += lastSpace + 1
-			} else {
-				wrappedLines = append(wrappedLines, line[currentPos:endPos])
-				currentPos = endPos
-			}
+	width := 0
+	if s.wordWrap {
+		width = s.wrapWidth()
+	}
+
+	matches := s.matchFilters(all)
+
+	rows := make([]string, 0, len(all))
+	s.lineLevels = s.lineLevels[:0]
+	s.lineRows = s.lineRows[:0]
+	s.lineIDs = s.lineIDs[:0]
+	for i, line := range all {
+		if levels[i] != logs.LevelNone && levels[i] < s.minLevel {
+			continue
+		}
+		if !matches[i] {
+			continue
+		}
+
+		s.lineLevels = append(s.lineLevels, levels[i])
+		s.lineRows = append(s.lineRows, len(rows))
+		s.lineIDs = append(s.lineIDs, first+i)
+
+		for _, row := range strings.Split(s.render(line, first+i, colorizer), "\n") {
+			rows = append(rows, wrapLine(row, width)...)
+		}
+	}
+	s.visibleLines = len(s.lineIDs)
+
+	for id := range s.expanded {
+		if id < first {
+			delete(s.expanded, id)
 		}
 	}
 
-	return strings.Join(wrappedLines, "\n")
+	s.viewport.SetContent(strings.Join(rows, "\n"))
+	s.updateViewportStyle()
 }
 
-func (s *ScrollView) UpdateContent(colorizer func(string) string) {
-	content := s.buffer.String()
+func (s *ScrollView) render(line string, id int, colorizer func(string) string) string {
+	if s.formatter != nil {
+		if out, ok := s.formatter(line, s.expanded[id]); ok {
+			return out
+		}
+	}
 	if colorizer != nil {
-		content = colorizer(content)
+		return colorizer(line)
 	}
-	if s.wordWrap {
-		content = s.wrapContent(content)
+	return line
+}
+
+func (s *ScrollView) SetFormatter(formatter LineFormatter) {
+	s.formatter = formatter
+	s.expanded = nil
+}
+
+func (s *ScrollView) HasFormatter() bool {
+	return s.formatter != nil
+}
+
+// LineAt returns the sequence number of the buffered line rendered at the
+// given content row, accounting for filters, wrapping and expanded lines.
+func (s *ScrollView) LineAt(row int) (int, bool) {
+	for i := len(s.lineRows) - 1; i >= 0; i-- {
+		if s.lineRows[i] <= row {
+			return s.lineIDs[i], true
+		}
+	}
+	return 0, false
+}
+
+// ToggleExpanded switches the line with the given sequence number between
+// its compact and detailed rendering.
+func (s *ScrollView) ToggleExpanded(id int) {
+	if s.expanded == nil {
+		s.expanded = make(map[int]bool)
+	}
+	if s.expanded[id] {
+		delete(s.expanded, id)
+	} else {
+		s.expanded[id] = true
 	}
-	s.viewport.SetContent(content)
-	s.updateViewportStyle()
 }
 
 func (s *ScrollView) ViewportModel() *viewport.Model {
@@ -0,0 +1,143 @@
+package components
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toyz/ssh-thing/logs"
+)
+
+// rateWindow is how many seconds of output LinesPerSecond averages over.
+const rateWindow = 10
+
+// TabStats tracks activity of a tab's output since the program started. It is
+// updated from the goroutine streaming the output and read by the UI.
+type TabStats struct {
+	mu sync.Mutex
+
+	connectedAt time.Time
+	lastOutput  time.Time
+	lastLine    string
+	pending     string
+	lines       int
+	errors      int
+
+	// Lines received since the tab was last viewed.
+	unread      int
+	unreadError bool
+
+	// Lines received per second over the last rateWindow seconds, indexed by
+	// unix second modulo rateWindow.
+	buckets    [rateWindow]int
+	bucketTime [rateWindow]int64
+
+	// Classifier assigns a level to each complete line. It defaults to
+	// logs.DetectLevel.
+	Classifier func(string) logs.Level
+}
+
+// TabStatsSnapshot is a consistent copy of a tab's statistics.
+type TabStatsSnapshot struct {
+	ConnectedAt    time.Time
+	LastOutput     time.Time
+	LastLine       string
+	Lines          int
+	Errors         int
+	LinesPerSecond float64
+	Unread         int
+	UnreadError    bool
+}
+
+func NewTabStats() *TabStats {
+	return &TabStats{}
+}
+
+func (s *TabStats) Connected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connectedAt = time.Now()
+}
+
+// Record counts the complete lines in a chunk of output. A trailing partial
+// line is kept until the rest of it arrives.
+func (s *TabStats) Record(output string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastOutput = now
+
+	chunks := strings.Split(s.pending+output, "\n")
+	s.pending = chunks[len(chunks)-1]
+
+	for _, line := range chunks[:len(chunks)-1] {
+		line = strings.TrimRight(line, "\r")
+		s.lines++
+		s.unread++
+		s.addToBucket(now.Unix())
+		if strings.TrimSpace(line) != "" {
+			s.lastLine = line
+		}
+
+		if s.classify(line) >= logs.LevelError {
+			s.errors++
+			s.unreadError = true
+		}
+	}
+}
+
+// SetClassifier replaces the Classifier while output may be recorded.
+func (s *TabStats) SetClassifier(classifier func(string) logs.Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Classifier = classifier
+}
+
+func (s *TabStats) classify(line string) logs.Level {
+	if s.Classifier != nil {
+		return s.Classifier(line)
+	}
+	return logs.DetectLevel(line)
+}
+
+func (s *TabStats) addToBucket(second int64) {
+	i := second % rateWindow
+	if s.bucketTime[i] != second {
+		s.bucketTime[i] = second
+		s.buckets[i] = 0
+	}
+	s.buckets[i]++
+}
+
+func (s *TabStats) Snapshot() TabStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Unix()
+	total := 0
+	for i, second := range s.bucketTime {
+		if now-second < rateWindow {
+			total += s.buckets[i]
+		}
+	}
+
+	return TabStatsSnapshot{
+		ConnectedAt:    s.connectedAt,
+		LastOutput:     s.lastOutput,
+		LastLine:       s.lastLine,
+		Lines:          s.lines,
+		Errors:         s.errors,
+		LinesPerSecond: float64(total) / rateWindow,
+		Unread:         s.unread,
+		UnreadError:    s.unreadError,
+	}
+}
+
+// MarkRead resets the unread count, e.g. when the tab is shown.
+func (s *TabStats) MarkRead() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unread = 0
+	s.unreadError = false
+}
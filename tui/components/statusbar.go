@@ -10,16 +10,23 @@ type StatusBar struct {
 	Width int
 }
 
+// StatusItem is an extra key/value section rendered after the fixed
+// STATUS, SERVER and SCROLL sections.
+type StatusItem struct {
+	Key   string
+	Value string
+}
+
 func NewStatusBar() *StatusBar {
 	return &StatusBar{}
 }
 
-func (s *StatusBar) View(serverName string, status string, scrollPos string, helpView string) string {
+func (s *StatusBar) View(serverName string, status string, scrollPos string, helpView string, items ...StatusItem) string {
 	w := lipgloss.Width
 
 	statusKey := StatusBarStyle.Render("STATUS")
 	statusVal := StatusText.Copy().
-		Foreground(statusColor(status)).
+		Foreground(StatusColor(status)).
 		Render(status)
 
 	serverKey := StatusBarStyle.Render("SERVER")
@@ -31,18 +38,28 @@ func (s *StatusBar) View(serverName string, status string, scrollPos string, hel
 	// Add a small gap between sections using the background color of the text
 	gap := StatusText.Render(" ")
 
-	statusBlock := lipgloss.JoinHorizontal(lipgloss.Top,
+	sections := []string{
 		statusKey, statusVal, gap,
 		serverKey, serverVal, gap,
 		scrollKey, scrollVal, gap,
-	)
+	}
+
+	for _, item := range items {
+		sections = append(sections,
+			StatusBarStyle.Render(item.Key),
+			StatusText.Render(item.Value),
+			gap,
+		)
+	}
+
+	statusBlock := lipgloss.JoinHorizontal(lipgloss.Top, sections...)
 
 	// Fill the rest of the width with the status bar background color
 	availWidth := s.Width - w(statusBlock)
 	if availWidth < 0 {
 		availWidth = 0
 	}
-	
+
 	helpVal := StatusText.Copy().
 		Width(availWidth).
 		Align(lipgloss.Right).
@@ -54,7 +71,8 @@ func (s *StatusBar) View(serverName string, status string, scrollPos string, hel
 	)
 }
 
-func statusColor(status string) lipgloss.Color {
+// StatusColor is the color used to show a connection status.
+func StatusColor(status string) lipgloss.Color {
 	switch strings.ToLower(status) {
 	case "connected":
 		return lipgloss.Color("#00FF00") // Green
@@ -80,6 +80,38 @@ var (
 				Foreground(lipgloss.Color("#ffb86c")).
 				Bold(true)
 
+	PromptStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#f8f8f2")).
+			Background(lipgloss.Color("#282a36"))
+
+	PromptLabelStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#50fa7b")). // Dracula Green
+				Bold(true)
+
+	TabGroupStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#8be9fd")). // Dracula Cyan
+			Bold(true).
+			Padding(0, 1)
+
+	UnreadBadgeStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#f1fa8c")) // Dracula Yellow
+
+	ErrorBadgeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ff5555")). // Dracula Red
+			Bold(true)
+
+	PaletteStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#bd93f9")). // Dracula Purple
+			Padding(0, 1)
+
+	PaletteSelectedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#282a36")).
+				Background(lipgloss.Color("#bd93f9"))
+
+	PaletteHintStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#6272a4")) // Dracula Comment
+
 	ScrollUpIndicator   = "↑"
 	ScrollDownIndicator = "↓"
 )
@@ -1,21 +1,28 @@
 package components
 
 import (
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/logs"
 	"github.com/toyz/ssh-thing/ssh"
 )
 
 type TabContent struct {
+	Server     *config.SSHServer
 	Client     *ssh.Client
 	ScrollView *ScrollView
 	HasError   bool
 	ErrorMsg   string
 	Name       string
+	Colorizer  func(string) string
+	Parser     logs.Parser
+	Stats      *TabStats
 }
 
 func NewTabContent(name string) *TabContent {
 	return &TabContent{
 		Name:       name,
 		ScrollView: NewScrollView(),
+		Stats:      NewTabStats(),
 		HasError:   false,
 	}
 }
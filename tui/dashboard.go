@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+type dashboardSort int
+
+const (
+	sortByName dashboardSort = iota
+	sortByStatus
+	sortByRate
+	sortByErrors
+	sortByIdle
+)
+
+func (s dashboardSort) String() string {
+	switch s {
+	case sortByStatus:
+		return "status"
+	case sortByRate:
+		return "rate"
+	case sortByErrors:
+		return "errors"
+	case sortByIdle:
+		return "idle"
+	}
+	return "name"
+}
+
+func (s dashboardSort) next() dashboardSort {
+	return (s + 1) % (sortByIdle + 1)
+}
+
+// statusRank orders connection states so problems sort first.
+var statusRank = map[string]int{
+	"Error":      0,
+	"Connecting": 1,
+	"Connected":  2,
+}
+
+type dashboardTickMsg struct {
+	gen int
+}
+
+// dashboardTick refreshes the dashboard every second so durations and rates
+// stay current even when no output arrives.
+func dashboardTick(gen int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return dashboardTickMsg{gen: gen}
+	})
+}
+
+func (m Model) tabStatus(tab *components.TabContent) string {
+	switch {
+	case m.merged != nil && tab == m.merged.tab:
+		return "Merged"
+	case tab.HasError:
+		return "Error"
+	case tab.Client == nil:
+		return "Connecting"
+	}
+	return "Connected"
+}
+
+// dashboardEntries returns the server tabs in dashboard order together with
+// their cards.
+func (m Model) dashboardEntries() ([]*components.TabContent, []components.DashboardCard) {
+	var tabs []*components.TabContent
+	var cards []components.DashboardCard
+
+	for _, tab := range m.tabContents {
+		if tab.Server == nil {
+			continue
+		}
+		host := tab.Server.Host
+		if tab.Server.User != "" {
+			host = tab.Server.User + "@" + host
+		}
+
+		tabs = append(tabs, tab)
+		cards = append(cards, components.DashboardCard{
+			Name:   tab.Name,
+			Host:   host,
+			Status: m.tabStatus(tab),
+			Stats:  tab.Stats.Snapshot(),
+		})
+	}
+
+	order := make([]int, len(cards))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := cards[order[i]], cards[order[j]]
+		switch m.dashboardSort {
+		case sortByStatus:
+			if statusRank[a.Status] != statusRank[b.Status] {
+				return statusRank[a.Status] < statusRank[b.Status]
+			}
+		case sortByRate:
+			if a.Stats.LinesPerSecond != b.Stats.LinesPerSecond {
+				return a.Stats.LinesPerSecond > b.Stats.LinesPerSecond
+			}
+		case sortByErrors:
+			if a.Stats.Errors != b.Stats.Errors {
+				return a.Stats.Errors > b.Stats.Errors
+			}
+		case sortByIdle:
+			// Longest silent first; servers that never produced output lead.
+			if !a.Stats.LastOutput.Equal(b.Stats.LastOutput) {
+				return a.Stats.LastOutput.Before(b.Stats.LastOutput)
+			}
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+
+	sortedTabs := make([]*components.TabContent, len(order))
+	sortedCards := make([]components.DashboardCard, len(order))
+	for i, j := range order {
+		sortedTabs[i] = tabs[j]
+		sortedCards[i] = cards[j]
+	}
+	return sortedTabs, sortedCards
+}
+
+func (m Model) toggleDashboard() (tea.Model, tea.Cmd) {
+	m.showDashboard = !m.showDashboard
+	if !m.showDashboard {
+		return m, nil
+	}
+
+	m.dashboardSel = 0
+	if tabs, _ := m.dashboardEntries(); m.activeTab < len(m.tabContents) {
+		for i, tab := range tabs {
+			if tab == m.tabContents[m.activeTab] {
+				m.dashboardSel = i
+			}
+		}
+	}
+
+	m.dashboardGen++
+	return m, dashboardTick(m.dashboardGen)
+}
+
+// openDashboardEntry leaves the dashboard and switches to the selected server.
+func (m Model) openDashboardEntry(index int) (tea.Model, tea.Cmd) {
+	tabs, _ := m.dashboardEntries()
+	if index < 0 || index >= len(tabs) {
+		return m, nil
+	}
+
+	m.showDashboard = false
+	return m.selectTab(m.tabIndex(tabs[index])), nil
+}
+
+func (m Model) updateDashboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	tabs, _ := m.dashboardEntries()
+	cols := m.dashboard.Columns()
+
+	move := func(delta int) {
+		if next := m.dashboardSel + delta; next >= 0 && next < len(tabs) {
+			m.dashboardSel = next
+		}
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+
+	case key.Matches(msg, m.keys.CommandPalette):
+		return m.openPalette()
+
+	case key.Matches(msg, m.keys.ToggleDashboard), msg.String() == "esc":
+		return m.toggleDashboard()
+
+	case key.Matches(msg, m.keys.Select):
+		return m.openDashboardEntry(m.dashboardSel)
+
+	case key.Matches(msg, m.keys.DashboardSort):
+		m.dashboardSort = m.dashboardSort.next()
+
+	case key.Matches(msg, m.keys.Left), key.Matches(msg, m.keys.TabPrev):
+		move(-1)
+
+	case key.Matches(msg, m.keys.Right), key.Matches(msg, m.keys.TabNext):
+		move(1)
+
+	case key.Matches(msg, m.keys.Up):
+		move(-cols)
+
+	case key.Matches(msg, m.keys.Down):
+		move(cols)
+
+	case key.Matches(msg, m.keys.Home):
+		m.dashboardSel = 0
+
+	case key.Matches(msg, m.keys.End):
+		m.dashboardSel = len(tabs) - 1
+	}
+
+	return m, nil
+}
+
+func (m Model) dashboardMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Type != tea.MouseLeft {
+		return m, nil
+	}
+
+	tabs, _ := m.dashboardEntries()
+	index, ok := m.dashboard.CardAt(msg.X-m.dashboard.X, msg.Y-m.dashboard.Y, m.dashboardSel, len(tabs))
+	if !ok {
+		return m, nil
+	}
+	return m.openDashboardEntry(index)
+}
+
+func (m Model) dashboardView(x, y, width, height int) string {
+	_, cards := m.dashboardEntries()
+	if m.dashboardSel >= len(cards) {
+		m.dashboardSel = len(cards) - 1
+	}
+
+	m.dashboard.X, m.dashboard.Y = x, y
+	m.dashboard.Width, m.dashboard.Height = width, height
+	return m.dashboard.View(cards, m.dashboardSel)
+}
@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+// detailRow is one setting in the server details view.
+type detailRow struct {
+	key   string
+	value string
+}
+
+// serverDetails lists a server's effective settings after defaults and
+// templates were applied, and the inventory it came from.
+func serverDetails(tab *components.TabContent) []detailRow {
+	server := tab.Server
+
+	var rules []string
+	for _, rule := range server.Highlights {
+		name := rule.Name
+		if name == "" {
+			name = rule.Pattern
+		}
+		rules = append(rules, name)
+	}
+
+	return []detailRow{
+		{"name", server.Name},
+		{"source", server.Source},
+		{"file", server.File},
+		{"extends", server.Extends},
+		{"host", server.Host},
+		{"user", server.User},
+		{"port", strconv.Itoa(server.Port)},
+		{"private_key_path", server.PrivateKeyPath},
+		{"private_key_passphrase", mask(server.PrivateKeyPassphrase)},
+		{"password", mask(server.Password)},
+		{"password_command", server.PasswordCommand},
+		{"password_file", server.PasswordFile},
+		{"commands", strings.Join(server.Commands, "\n")},
+		{"group", server.Group},
+		{"tags", strings.Join(server.Tags, ", ")},
+		{"format", server.Format},
+		{"highlight", strings.Join(rules, ", ")},
+	}
+}
+
+// mask hides a secret, but shows which vault entry it refers to.
+func mask(secret string) string {
+	switch {
+	case secret == "":
+		return ""
+	case strings.HasPrefix(secret, config.VaultPrefix):
+		return secret
+	default:
+		return strings.Repeat("*", 8)
+	}
+}
+
+// detailsView shows the settings of the active server.
+func (m Model) detailsView(width, height int) string {
+	boxWidth := formWidth
+	if boxWidth > width-2 {
+		boxWidth = width - 2
+	}
+	labelWidth := 18
+
+	var lines []string
+	if tab := m.tabContents[m.activeTab]; tab.Server == nil {
+		lines = append(lines, components.PaletteHintStyle.Render("Select a server tab to see its settings"))
+	} else {
+		lines = append(lines, serverDetailsLines(tab, labelWidth, boxWidth-4-labelWidth)...)
+	}
+
+	lines = append(lines, "", components.PaletteHintStyle.Render("i or esc to close"))
+
+	box := components.PaletteStyle.Width(boxWidth - 2).Render(strings.Join(lines, "\n"))
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Top, box)
+}
+
+func serverDetailsLines(tab *components.TabContent, labelWidth, valueWidth int) []string {
+	lines := []string{components.PromptLabelStyle.Render(tab.Name), ""}
+	for _, row := range serverDetails(tab) {
+		if row.value == "" {
+			continue
+		}
+
+		value := components.PromptStyle.Width(valueWidth).Render(row.value)
+		if origin, ok := tab.Server.Inherited[row.key]; ok {
+			value = lipgloss.JoinVertical(lipgloss.Left, value, components.PaletteHintStyle.Render("from "+origin))
+		}
+		label := components.PaletteHintStyle.Width(labelWidth).Render(row.key)
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, label, value))
+	}
+	return lines
+}
@@ -0,0 +1,193 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/toyz/ssh-thing/logs"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+type filterMode int
+
+const (
+	filterNone filterMode = iota
+	filterInclude
+	filterExclude
+	filterQuery
+	// promptBroadcast reuses the filter prompt to read a command to run on
+	// every server in a group.
+	promptBroadcast
+)
+
+func newFilterInput() textinput.Model {
+	input := textinput.New()
+	input.Placeholder = "regex"
+	input.TextStyle = components.PromptStyle
+	input.PromptStyle = components.PromptLabelStyle
+	return input
+}
+
+func (m Model) startFilter(mode filterMode) (Model, tea.Cmd) {
+	if m.activeTab >= len(m.tabContents) || m.tabContents[m.activeTab].HasError {
+		return m, nil
+	}
+
+	m.filterMode = mode
+	m.filterErr = ""
+	m.filterInput.Reset()
+	switch mode {
+	case filterExclude:
+		m.filterInput.Prompt = "exclude: "
+		m.filterInput.Placeholder = "regex"
+	case filterQuery:
+		m.filterInput.Prompt = "query: "
+		m.filterInput.Placeholder = "level>=warn AND status>=500"
+	default:
+		m.filterInput.Prompt = "include: "
+		m.filterInput.Placeholder = "regex"
+	}
+
+	return m, m.filterInput.Focus()
+}
+
+func (m Model) updateFilterInput(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.filterMode = filterNone
+		m.filterErr = ""
+		m.filterInput.Blur()
+		return m, nil
+
+	case tea.KeyEnter:
+		expr := m.filterInput.Value()
+		if expr == "" {
+			m.filterMode = filterNone
+			m.filterInput.Blur()
+			return m, nil
+		}
+
+		if m.filterMode == promptBroadcast {
+			m.broadcast(expr)
+			m.filterMode = filterNone
+			m.filterInput.Blur()
+			return m, nil
+		}
+
+		if m.activeTab < len(m.tabContents) {
+			tab := m.tabContents[m.activeTab]
+			filter, err := newLineFilter(m.filterMode, expr, tab)
+			if err != nil {
+				m.filterErr = err.Error()
+				return m, nil
+			}
+
+			tab.ScrollView.AddFilter(filter)
+			m.refreshTab(tab)
+		}
+
+		m.filterMode = filterNone
+		m.filterErr = ""
+		m.filterInput.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.filterErr = ""
+	if m.filterMode == filterQuery && m.filterInput.Value() != "" {
+		if _, err := logs.ParseQuery(m.filterInput.Value()); err != nil {
+			m.filterErr = err.Error()
+		}
+	}
+	return m, cmd
+}
+
+func newLineFilter(mode filterMode, expr string, tab *components.TabContent) (components.LineFilter, error) {
+	if mode == filterQuery {
+		query, err := logs.ParseQuery(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &queryFilter{parser: tab.Parser, query: query}, nil
+	}
+
+	return components.NewRegexFilter(expr, mode == filterExclude)
+}
+
+// queryFilter keeps lines whose parsed fields satisfy a query such as
+// level>=warn AND status>=500. Lines that cannot be parsed are hidden.
+type queryFilter struct {
+	parser logs.Parser
+	query  *logs.Query
+}
+
+func (f *queryFilter) Match(line string) bool {
+	entry, ok := f.parser.Parse(line)
+	return ok && f.query.Match(entry)
+}
+
+func (f *queryFilter) String() string {
+	return "{" + f.query.String() + "}"
+}
+
+func (m Model) clearFilters() Model {
+	if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
+		tab := m.tabContents[m.activeTab]
+		tab.ScrollView.ClearFilters()
+		m.refreshTab(tab)
+	}
+	return m
+}
+
+func (m Model) filterPromptView() string {
+	if m.filterMode == filterNone {
+		return ""
+	}
+
+	m.filterInput.Width = m.width - len(m.filterInput.Prompt) - 1
+	view := m.filterInput.View()
+	if m.filterErr != "" {
+		view += " " + components.ErrorStyle.Render(m.filterErr)
+	}
+	return view
+}
+
+func filterStatus(view *components.ScrollView) (components.StatusItem, bool) {
+	if !view.IsFiltered() {
+		return components.StatusItem{}, false
+	}
+
+	var exprs []string
+	for _, f := range view.Filters() {
+		exprs = append(exprs, f.String())
+	}
+
+	return components.StatusItem{
+		Key:   "FILTER",
+		Value: fmt.Sprintf("%s  %d of %d lines shown", strings.Join(exprs, " "), view.VisibleLineCount(), view.LineCount()),
+	}, true
+}
+
+func levelStatus(view *components.ScrollView) []components.StatusItem {
+	var items []components.StatusItem
+
+	if min := view.MinLevel(); min != logs.LevelNone {
+		items = append(items, components.StatusItem{Key: "LEVEL", Value: ">= " + min.String()})
+	}
+
+	counts := view.LevelCounts()
+	var parts []string
+	for _, level := range logs.Levels {
+		if n := counts[level]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", level.String()[:1], n))
+		}
+	}
+	if len(parts) > 0 {
+		items = append(items, components.StatusItem{Key: "LEVELS", Value: strings.Join(parts, " ")})
+	}
+
+	return items
+}
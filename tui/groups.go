@@ -0,0 +1,263 @@
+package tui
+
+import (
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+// tabBarRow is a row of the vertical tab bar: a group header when tab is -1,
+// otherwise the index of a tab.
+type tabBarRow struct {
+	group string
+	tab   int
+}
+
+func (r tabBarRow) isHeader() bool {
+	return r.tab < 0
+}
+
+// tabGroup is the group a tab is listed under. The merged tab and servers
+// without a group are listed at the top level.
+func tabGroup(tab *components.TabContent) string {
+	if tab.Server == nil {
+		return ""
+	}
+	return tab.Server.Group
+}
+
+func hasTag(tab *components.TabContent, tag string) bool {
+	if tab.Server == nil {
+		return false
+	}
+	for _, t := range tab.Server.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// tabFiltered reports whether the tag filter hides a tab. The merged tab is
+// never hidden.
+func (m Model) tabFiltered(tab *components.TabContent) bool {
+	return m.tagFilter != "" && tab.Server != nil && !hasTag(tab, m.tagFilter)
+}
+
+// groups returns the group names in the order they first appear in the
+// config.
+func (m Model) groups() []string {
+	var groups []string
+	seen := make(map[string]bool)
+	for _, tab := range m.tabContents {
+		if group := tabGroup(tab); group != "" && !seen[group] {
+			seen[group] = true
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// tabBarRows lays out the vertical tab bar as a tree: top level tabs first,
+// then each group's header followed by its tabs unless it is collapsed.
+// Groups with no tab left after tag filtering are omitted.
+func (m Model) tabBarRows() []tabBarRow {
+	var rows []tabBarRow
+	for i, tab := range m.tabContents {
+		if tabGroup(tab) == "" && !m.tabFiltered(tab) {
+			rows = append(rows, tabBarRow{tab: i})
+		}
+	}
+
+	for _, group := range m.groups() {
+		var members []tabBarRow
+		for i, tab := range m.tabContents {
+			if tabGroup(tab) == group && !m.tabFiltered(tab) {
+				members = append(members, tabBarRow{group: group, tab: i})
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+
+		rows = append(rows, tabBarRow{group: group, tab: -1})
+		if !m.collapsed[group] {
+			rows = append(rows, members...)
+		}
+	}
+	return rows
+}
+
+// tabOrder returns the indices of the tabs shown in the tab bar, in display
+// order. Tabs of collapsed groups are only skipped in the vertical tab bar.
+func (m Model) tabOrder() []int {
+	var order []int
+	if m.verticalTabs {
+		for _, row := range m.tabBarRows() {
+			if !row.isHeader() {
+				order = append(order, row.tab)
+			}
+		}
+		return order
+	}
+
+	groups := append([]string{""}, m.groups()...)
+	for _, group := range groups {
+		for i, tab := range m.tabContents {
+			if tabGroup(tab) == group && !m.tabFiltered(tab) {
+				order = append(order, i)
+			}
+		}
+	}
+	return order
+}
+
+// stepTab selects the tab delta places away from the active one in display
+// order.
+func (m Model) stepTab(delta int) Model {
+	order := m.tabOrder()
+	if len(order) == 0 {
+		return m
+	}
+
+	pos := -1
+	for i, index := range order {
+		if index == m.activeTab {
+			pos = i
+		}
+	}
+	if pos < 0 && delta < 0 {
+		// The active tab is hidden; step from just past the end.
+		pos = len(order)
+	}
+
+	return m.selectTab(order[((pos+delta)%len(order)+len(order))%len(order)])
+}
+
+// revealTab expands the group of a tab and clears a tag filter hiding it.
+func (m Model) revealTab(tab *components.TabContent) Model {
+	delete(m.collapsed, tabGroup(tab))
+	if m.tabFiltered(tab) {
+		m.tagFilter = ""
+	}
+	return m
+}
+
+func (m Model) toggleGroup(group string) Model {
+	if group == "" {
+		return m
+	}
+	m.collapsed[group] = !m.collapsed[group]
+	return m
+}
+
+// tags returns every tag used by a server, sorted.
+func (m Model) tags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tab := range m.tabContents {
+		if tab.Server == nil {
+			continue
+		}
+		for _, tag := range tab.Server.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// cycleTag moves the tag filter to the next tag, and back to showing every
+// tab after the last one.
+func (m Model) cycleTag() Model {
+	tags := m.tags()
+	next := ""
+	if m.tagFilter == "" && len(tags) > 0 {
+		next = tags[0]
+	} else {
+		for i, tag := range tags {
+			if tag == m.tagFilter && i+1 < len(tags) {
+				next = tags[i+1]
+			}
+		}
+	}
+	m.tagFilter = next
+
+	if m.activeTab < len(m.tabContents) && m.tabFiltered(m.tabContents[m.activeTab]) {
+		if order := m.tabOrder(); len(order) > 0 {
+			m = m.selectTab(order[0])
+		}
+	}
+	return m
+}
+
+// groupTabs returns the server tabs in the same group as the active tab.
+// From the merged tab every server tab is returned.
+func (m Model) groupTabs() []*components.TabContent {
+	if m.activeTab >= len(m.tabContents) {
+		return nil
+	}
+
+	active := m.tabContents[m.activeTab]
+	var tabs []*components.TabContent
+	for _, tab := range m.tabContents {
+		if tab.Server == nil || m.tabFiltered(tab) {
+			continue
+		}
+		if active.Server == nil || tabGroup(tab) == tabGroup(active) {
+			tabs = append(tabs, tab)
+		}
+	}
+	return tabs
+}
+
+func (m Model) groupName() string {
+	if m.activeTab >= len(m.tabContents) || m.tabContents[m.activeTab].Server == nil {
+		return "all servers"
+	}
+	if group := tabGroup(m.tabContents[m.activeTab]); group != "" {
+		return group
+	}
+	return "ungrouped servers"
+}
+
+func (m Model) reconnectGroup() (Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	for _, tab := range m.groupTabs() {
+		cmds = append(cmds, m.reconnectTab(tab))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m Model) clearGroup() Model {
+	for _, tab := range m.groupTabs() {
+		tab.ScrollView.Clear()
+		m.refreshTab(tab)
+	}
+	return m
+}
+
+func (m Model) startBroadcast() (Model, tea.Cmd) {
+	m.filterMode = promptBroadcast
+	m.filterErr = ""
+	m.filterInput.Reset()
+	m.filterInput.Prompt = "broadcast to " + m.groupName() + ": "
+	m.filterInput.Placeholder = "command"
+	return m, m.filterInput.Focus()
+}
+
+// broadcast runs a command on every connected server in the active tab's
+// group. The output is shown in each server's tab.
+func (m Model) broadcast(command string) {
+	for _, tab := range m.groupTabs() {
+		if tab.Client != nil && !tab.HasError {
+			tab.ScrollView.Append("$ " + command + "\n")
+			tab.Client.Exec(command)
+			m.refreshTab(tab)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/toyz/ssh-thing/config"
+)
+
+type highlightRule struct {
+	pattern *regexp.Regexp
+	group   int
+	style   lipgloss.Style
+}
+
+// highlighter colors output using a precompiled set of rules. Matches are
+// collected per line first and rendered in a single pass, so rules never
+// match inside escape sequences inserted by earlier rules.
+type highlighter struct {
+	rules []highlightRule
+}
+
+// highlightCache shares compiled patterns between servers so identical rules
+// are only compiled once.
+type highlightCache map[string]*regexp.Regexp
+
+func (c highlightCache) newHighlighter(rules []config.HighlightRule) (*highlighter, error) {
+	h := &highlighter{}
+
+	for _, rule := range rules {
+		pattern, ok := c[rule.Pattern]
+		if !ok {
+			var err error
+			pattern, err = regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid highlight rule %q: %w", rule.Name, err)
+			}
+			c[rule.Pattern] = pattern
+		}
+
+		if rule.Group < 0 || rule.Group > pattern.NumSubexp() {
+			return nil, fmt.Errorf("invalid highlight rule %q: pattern has no capture group %d", rule.Name, rule.Group)
+		}
+
+		style := lipgloss.NewStyle().Bold(rule.Bold).Underline(rule.Underline)
+		if rule.Foreground != "" {
+			style = style.Foreground(lipgloss.Color(rule.Foreground))
+		}
+		if rule.Background != "" {
+			style = style.Background(lipgloss.Color(rule.Background))
+		}
+
+		h.rules = append(h.rules, highlightRule{
+			pattern: pattern,
+			group:   rule.Group,
+			style:   style,
+		})
+	}
+
+	return h, nil
+}
+
+func (h *highlighter) Apply(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = h.applyLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (h *highlighter) applyLine(line string) string {
+	if line == "" {
+		return line
+	}
+
+	owner := make([]int, len(line))
+	for i := range owner {
+		owner[i] = -1
+	}
+
+	matched := false
+	for r, rule := range h.rules {
+		for _, loc := range rule.pattern.FindAllStringSubmatchIndex(line, -1) {
+			start, end := loc[2*rule.group], loc[2*rule.group+1]
+			if start < 0 || start == end || !unclaimed(owner[start:end]) {
+				continue
+			}
+			for i := start; i < end; i++ {
+				owner[i] = r
+			}
+			matched = true
+		}
+	}
+
+	if !matched {
+		return line
+	}
+
+	var b strings.Builder
+	start := 0
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && owner[i] == owner[start] {
+			continue
+		}
+		if owner[start] < 0 {
+			b.WriteString(line[start:i])
+		} else {
+			b.WriteString(h.rules[owner[start]].style.Render(line[start:i]))
+		}
+		start = i
+	}
+	return b.String()
+}
+
+func unclaimed(owner []int) bool {
+	for _, o := range owner {
+		if o >= 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 
 	"github.com/charmbracelet/bubbles/key"
-	"github.com/pelletier/go-toml/v2"
+	"github.com/toyz/ssh-thing/config"
 )
 
 type KeyBindingsMap struct {
@@ -26,12 +27,52 @@ type KeyBindingsMap struct {
 	ClearBuffer       []string `toml:"clearBuffer"`
 	ToggleWordWrap    []string `toml:"toggleWordWrap"`
 	ToggleTabPosition []string `toml:"toggleTabPosition"`
+	FilterInclude     []string `toml:"filterInclude"`
+	FilterExclude     []string `toml:"filterExclude"`
+	ClearFilters      []string `toml:"clearFilters"`
+	CycleLevel        []string `toml:"cycleLevel"`
+	NextError         []string `toml:"nextError"`
+	PrevError         []string `toml:"prevError"`
+	ToggleStructured  []string `toml:"toggleStructured"`
+	ExpandLine        []string `toml:"expandLine"`
+	FilterQuery       []string `toml:"filterQuery"`
+	ToggleMerge       []string `toml:"toggleMerge"`
+	ToggleMergeOrder  []string `toml:"toggleMergeOrder"`
+	SplitVertical     []string `toml:"splitVertical"`
+	SplitHorizontal   []string `toml:"splitHorizontal"`
+	ClosePane         []string `toml:"closePane"`
+	NextPane          []string `toml:"nextPane"`
+	PrevPane          []string `toml:"prevPane"`
+	ToggleDashboard   []string `toml:"toggleDashboard"`
+	DashboardSort     []string `toml:"dashboardSort"`
+	Select            []string `toml:"selectEntry"`
+	CommandPalette    []string `toml:"commandPalette"`
+	ToggleGroup       []string `toml:"toggleGroup"`
+	CycleTag          []string `toml:"cycleTag"`
+	ReconnectGroup    []string `toml:"reconnectGroup"`
+	BroadcastGroup    []string `toml:"broadcastGroup"`
+	ClearGroup        []string `toml:"clearGroup"`
+	AddServer         []string `toml:"addServer"`
+	EditServer        []string `toml:"editServer"`
+	DeleteServer      []string `toml:"deleteServer"`
+	ServerDetails     []string `toml:"serverDetails"`
 }
 
 type KeyBindingsConfig struct {
+	// Version is the version of the file format; see KeybindsMigrations.
+	Version int `toml:"version,omitempty"`
+
+	// Schema is the JSON Schema editors use to check and complete the file.
+	Schema string `toml:"$schema,omitempty"`
+
 	Keybinds KeyBindingsMap `toml:"keybinds"`
 }
 
+// KeybindsMigrations upgrade keybinds files.
+var KeybindsMigrations = config.Migrations{
+	{Version: 1, Description: "add the version key"},
+}
+
 type KeyMap struct {
 	Up                key.Binding
 	Down              key.Binding
@@ -49,6 +90,35 @@ type KeyMap struct {
 	ClearBuffer       key.Binding
 	ToggleWordWrap    key.Binding
 	ToggleTabPosition key.Binding
+	FilterInclude     key.Binding
+	FilterExclude     key.Binding
+	ClearFilters      key.Binding
+	CycleLevel        key.Binding
+	NextError         key.Binding
+	PrevError         key.Binding
+	ToggleStructured  key.Binding
+	ExpandLine        key.Binding
+	FilterQuery       key.Binding
+	ToggleMerge       key.Binding
+	ToggleMergeOrder  key.Binding
+	SplitVertical     key.Binding
+	SplitHorizontal   key.Binding
+	ClosePane         key.Binding
+	NextPane          key.Binding
+	PrevPane          key.Binding
+	ToggleDashboard   key.Binding
+	DashboardSort     key.Binding
+	Select            key.Binding
+	CommandPalette    key.Binding
+	ToggleGroup       key.Binding
+	CycleTag          key.Binding
+	ReconnectGroup    key.Binding
+	BroadcastGroup    key.Binding
+	ClearGroup        key.Binding
+	AddServer         key.Binding
+	EditServer        key.Binding
+	DeleteServer      key.Binding
+	ServerDetails     key.Binding
 }
 
 func (k KeyMap) ShortHelp() []key.Binding {
@@ -60,7 +130,27 @@ func (k KeyMap) FullHelp() [][]key.Binding {
 		{k.Left, k.Right, k.TabPrev, k.TabNext},
 		{k.Up, k.Down, k.PageUp, k.PageDown, k.Home, k.End, k.ResetScroll},
 		{k.ToggleColor, k.ToggleWordWrap, k.ClearBuffer, k.Quit},
+		{k.FilterInclude, k.FilterExclude, k.FilterQuery, k.ClearFilters},
+		{k.CycleLevel, k.NextError, k.PrevError},
+		{k.ToggleStructured, k.ExpandLine},
+		{k.ToggleMerge, k.ToggleMergeOrder},
+		{k.SplitVertical, k.SplitHorizontal, k.ClosePane, k.NextPane, k.PrevPane},
+		{k.ToggleDashboard, k.DashboardSort, k.Select, k.CommandPalette},
+		{k.ToggleGroup, k.CycleTag, k.ReconnectGroup, k.BroadcastGroup, k.ClearGroup},
+		{k.AddServer, k.EditServer, k.DeleteServer, k.ServerDetails},
+	}
+}
+
+// Bindings returns every binding in the key map, in declaration order.
+func (k KeyMap) Bindings() []key.Binding {
+	v := reflect.ValueOf(k)
+	bindings := make([]key.Binding, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if binding, ok := v.Field(i).Interface().(key.Binding); ok {
+			bindings = append(bindings, binding)
+		}
 	}
+	return bindings
 }
 
 var bindingDescriptions = map[string]string{
@@ -80,6 +170,35 @@ var bindingDescriptions = map[string]string{
 	"clearBuffer":       "clear buffer",
 	"toggleWordWrap":    "toggle word wrap",
 	"toggleTabPosition": "toggle tab position",
+	"filterInclude":     "filter matching lines",
+	"filterExclude":     "hide matching lines",
+	"clearFilters":      "clear filters",
+	"cycleLevel":        "cycle minimum level",
+	"nextError":         "next error",
+	"prevError":         "previous error",
+	"toggleStructured":  "toggle structured view",
+	"expandLine":        "expand line",
+	"filterQuery":       "filter by query",
+	"toggleMerge":       "toggle tab in All",
+	"toggleMergeOrder":  "toggle All ordering",
+	"splitVertical":     "split side by side",
+	"splitHorizontal":   "split stacked",
+	"closePane":         "close pane",
+	"nextPane":          "next pane",
+	"prevPane":          "previous pane",
+	"toggleDashboard":   "dashboard",
+	"dashboardSort":     "sort dashboard",
+	"selectEntry":       "open selected",
+	"commandPalette":    "command palette",
+	"toggleGroup":       "collapse group",
+	"cycleTag":          "filter by tag",
+	"reconnectGroup":    "reconnect group",
+	"broadcastGroup":    "broadcast to group",
+	"clearGroup":        "clear group buffers",
+	"addServer":         "add server",
+	"editServer":        "edit server",
+	"deleteServer":      "delete server",
+	"serverDetails":     "server details",
 }
 
 func DefaultKeyMap() KeyMap {
@@ -148,6 +267,122 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("p"),
 			key.WithHelp("p", "toggle tab position"),
 		),
+		FilterInclude: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "filter matching lines"),
+		),
+		FilterExclude: key.NewBinding(
+			key.WithKeys("\\"),
+			key.WithHelp("\\", "hide matching lines"),
+		),
+		ClearFilters: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "clear filters"),
+		),
+		CycleLevel: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "cycle minimum level"),
+		),
+		NextError: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "next error"),
+		),
+		PrevError: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "previous error"),
+		),
+		ToggleStructured: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "toggle structured view"),
+		),
+		ExpandLine: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "expand line"),
+		),
+		FilterQuery: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "filter by query"),
+		),
+		ToggleMerge: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "toggle tab in All"),
+		),
+		ToggleMergeOrder: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "toggle All ordering"),
+		),
+		SplitVertical: key.NewBinding(
+			key.WithKeys("|"),
+			key.WithHelp("|", "split side by side"),
+		),
+		SplitHorizontal: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", "split stacked"),
+		),
+		ClosePane: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "close pane"),
+		),
+		NextPane: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next pane"),
+		),
+		PrevPane: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous pane"),
+		),
+		ToggleDashboard: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "dashboard"),
+		),
+		DashboardSort: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "sort dashboard"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "open selected"),
+		),
+		CommandPalette: key.NewBinding(
+			key.WithKeys("ctrl+p"),
+			key.WithHelp("ctrl+p", "command palette"),
+		),
+		ToggleGroup: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", "collapse group"),
+		),
+		CycleTag: key.NewBinding(
+			key.WithKeys("#"),
+			key.WithHelp("#", "filter by tag"),
+		),
+		ReconnectGroup: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "reconnect group"),
+		),
+		BroadcastGroup: key.NewBinding(
+			key.WithKeys("B"),
+			key.WithHelp("B", "broadcast to group"),
+		),
+		ClearGroup: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "clear group buffers"),
+		),
+		AddServer: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "add server"),
+		),
+		EditServer: key.NewBinding(
+			key.WithKeys("ctrl+e"),
+			key.WithHelp("ctrl+e", "edit server"),
+		),
+		DeleteServer: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "delete server"),
+		),
+		ServerDetails: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "server details"),
+		),
 	}
 }
 
@@ -169,6 +404,35 @@ func DefaultKeyBindingsMap() KeyBindingsMap {
 		ClearBuffer:       []string{"ctrl+l"},
 		ToggleWordWrap:    []string{"w"},
 		ToggleTabPosition: []string{"p"},
+		FilterInclude:     []string{"/"},
+		FilterExclude:     []string{"\\"},
+		ClearFilters:      []string{"x"},
+		CycleLevel:        []string{"L"},
+		NextError:         []string{"e"},
+		PrevError:         []string{"E"},
+		ToggleStructured:  []string{"s"},
+		ExpandLine:        []string{"o"},
+		FilterQuery:       []string{"f"},
+		ToggleMerge:       []string{"m"},
+		ToggleMergeOrder:  []string{"t"},
+		SplitVertical:     []string{"|"},
+		SplitHorizontal:   []string{"-"},
+		ClosePane:         []string{"X"},
+		NextPane:          []string{"]"},
+		PrevPane:          []string{"["},
+		ToggleDashboard:   []string{"d"},
+		DashboardSort:     []string{"S"},
+		Select:            []string{"enter"},
+		CommandPalette:    []string{"ctrl+p"},
+		ToggleGroup:       []string{"g"},
+		CycleTag:          []string{"#"},
+		ReconnectGroup:    []string{"R"},
+		BroadcastGroup:    []string{"B"},
+		ClearGroup:        []string{"C"},
+		AddServer:         []string{"n"},
+		EditServer:        []string{"ctrl+e"},
+		DeleteServer:      []string{"D"},
+		ServerDetails:     []string{"i"},
 	}
 }
 
@@ -238,6 +502,122 @@ func (m KeyBindingsMap) ToKeyMap() KeyMap {
 			key.WithKeys(m.ToggleTabPosition...),
 			key.WithHelp(getHelpPrefix(m.ToggleTabPosition), bindingDescriptions["toggleTabPosition"]),
 		),
+		FilterInclude: key.NewBinding(
+			key.WithKeys(m.FilterInclude...),
+			key.WithHelp(getHelpPrefix(m.FilterInclude), bindingDescriptions["filterInclude"]),
+		),
+		FilterExclude: key.NewBinding(
+			key.WithKeys(m.FilterExclude...),
+			key.WithHelp(getHelpPrefix(m.FilterExclude), bindingDescriptions["filterExclude"]),
+		),
+		ClearFilters: key.NewBinding(
+			key.WithKeys(m.ClearFilters...),
+			key.WithHelp(getHelpPrefix(m.ClearFilters), bindingDescriptions["clearFilters"]),
+		),
+		CycleLevel: key.NewBinding(
+			key.WithKeys(m.CycleLevel...),
+			key.WithHelp(getHelpPrefix(m.CycleLevel), bindingDescriptions["cycleLevel"]),
+		),
+		NextError: key.NewBinding(
+			key.WithKeys(m.NextError...),
+			key.WithHelp(getHelpPrefix(m.NextError), bindingDescriptions["nextError"]),
+		),
+		PrevError: key.NewBinding(
+			key.WithKeys(m.PrevError...),
+			key.WithHelp(getHelpPrefix(m.PrevError), bindingDescriptions["prevError"]),
+		),
+		ToggleStructured: key.NewBinding(
+			key.WithKeys(m.ToggleStructured...),
+			key.WithHelp(getHelpPrefix(m.ToggleStructured), bindingDescriptions["toggleStructured"]),
+		),
+		ExpandLine: key.NewBinding(
+			key.WithKeys(m.ExpandLine...),
+			key.WithHelp(getHelpPrefix(m.ExpandLine), bindingDescriptions["expandLine"]),
+		),
+		FilterQuery: key.NewBinding(
+			key.WithKeys(m.FilterQuery...),
+			key.WithHelp(getHelpPrefix(m.FilterQuery), bindingDescriptions["filterQuery"]),
+		),
+		ToggleMerge: key.NewBinding(
+			key.WithKeys(m.ToggleMerge...),
+			key.WithHelp(getHelpPrefix(m.ToggleMerge), bindingDescriptions["toggleMerge"]),
+		),
+		ToggleMergeOrder: key.NewBinding(
+			key.WithKeys(m.ToggleMergeOrder...),
+			key.WithHelp(getHelpPrefix(m.ToggleMergeOrder), bindingDescriptions["toggleMergeOrder"]),
+		),
+		SplitVertical: key.NewBinding(
+			key.WithKeys(m.SplitVertical...),
+			key.WithHelp(getHelpPrefix(m.SplitVertical), bindingDescriptions["splitVertical"]),
+		),
+		SplitHorizontal: key.NewBinding(
+			key.WithKeys(m.SplitHorizontal...),
+			key.WithHelp(getHelpPrefix(m.SplitHorizontal), bindingDescriptions["splitHorizontal"]),
+		),
+		ClosePane: key.NewBinding(
+			key.WithKeys(m.ClosePane...),
+			key.WithHelp(getHelpPrefix(m.ClosePane), bindingDescriptions["closePane"]),
+		),
+		NextPane: key.NewBinding(
+			key.WithKeys(m.NextPane...),
+			key.WithHelp(getHelpPrefix(m.NextPane), bindingDescriptions["nextPane"]),
+		),
+		PrevPane: key.NewBinding(
+			key.WithKeys(m.PrevPane...),
+			key.WithHelp(getHelpPrefix(m.PrevPane), bindingDescriptions["prevPane"]),
+		),
+		ToggleDashboard: key.NewBinding(
+			key.WithKeys(m.ToggleDashboard...),
+			key.WithHelp(getHelpPrefix(m.ToggleDashboard), bindingDescriptions["toggleDashboard"]),
+		),
+		DashboardSort: key.NewBinding(
+			key.WithKeys(m.DashboardSort...),
+			key.WithHelp(getHelpPrefix(m.DashboardSort), bindingDescriptions["dashboardSort"]),
+		),
+		Select: key.NewBinding(
+			key.WithKeys(m.Select...),
+			key.WithHelp(getHelpPrefix(m.Select), bindingDescriptions["selectEntry"]),
+		),
+		CommandPalette: key.NewBinding(
+			key.WithKeys(m.CommandPalette...),
+			key.WithHelp(getHelpPrefix(m.CommandPalette), bindingDescriptions["commandPalette"]),
+		),
+		ToggleGroup: key.NewBinding(
+			key.WithKeys(m.ToggleGroup...),
+			key.WithHelp(getHelpPrefix(m.ToggleGroup), bindingDescriptions["toggleGroup"]),
+		),
+		CycleTag: key.NewBinding(
+			key.WithKeys(m.CycleTag...),
+			key.WithHelp(getHelpPrefix(m.CycleTag), bindingDescriptions["cycleTag"]),
+		),
+		ReconnectGroup: key.NewBinding(
+			key.WithKeys(m.ReconnectGroup...),
+			key.WithHelp(getHelpPrefix(m.ReconnectGroup), bindingDescriptions["reconnectGroup"]),
+		),
+		BroadcastGroup: key.NewBinding(
+			key.WithKeys(m.BroadcastGroup...),
+			key.WithHelp(getHelpPrefix(m.BroadcastGroup), bindingDescriptions["broadcastGroup"]),
+		),
+		ClearGroup: key.NewBinding(
+			key.WithKeys(m.ClearGroup...),
+			key.WithHelp(getHelpPrefix(m.ClearGroup), bindingDescriptions["clearGroup"]),
+		),
+		AddServer: key.NewBinding(
+			key.WithKeys(m.AddServer...),
+			key.WithHelp(getHelpPrefix(m.AddServer), bindingDescriptions["addServer"]),
+		),
+		EditServer: key.NewBinding(
+			key.WithKeys(m.EditServer...),
+			key.WithHelp(getHelpPrefix(m.EditServer), bindingDescriptions["editServer"]),
+		),
+		DeleteServer: key.NewBinding(
+			key.WithKeys(m.DeleteServer...),
+			key.WithHelp(getHelpPrefix(m.DeleteServer), bindingDescriptions["deleteServer"]),
+		),
+		ServerDetails: key.NewBinding(
+			key.WithKeys(m.ServerDetails...),
+			key.WithHelp(getHelpPrefix(m.ServerDetails), bindingDescriptions["serverDetails"]),
+		),
 	}
 }
 
@@ -252,41 +632,28 @@ func getHelpPrefix(keys []string) string {
 	return ""
 }
 
-func GetConfigDir() (string, error) {
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return "", err
-	}
-
-	sshThingConfigDir := filepath.Join(configDir, "ssh-thing")
-	err = os.MkdirAll(sshThingConfigDir, 0755)
-	if err != nil {
-		return "", err
-	}
-
-	return sshThingConfigDir, nil
-}
+// keybindsFiles are the names the keybinds file is looked for under in the
+// config directories.
+var keybindsFiles = config.FileNames("keybinds")
 
 func SaveKeyBindings(bindings KeyBindingsMap, filePath string) error {
 	if filePath == "" {
-		configDir, err := GetConfigDir()
+		path, err := config.FilePath(keybindsFiles...)
 		if err != nil {
 			return fmt.Errorf("failed to get config directory: %w", err)
 		}
-
-		filePath = filepath.Join(configDir, "keybinds.toml")
+		filePath = path
 	}
 
-	config := KeyBindingsConfig{
-		Keybinds: bindings,
-	}
-
-	tomlData, err := toml.Marshal(config)
+	data, err := config.Marshal(filePath, KeyBindingsConfig{Version: KeybindsMigrations.Current(), Keybinds: bindings})
 	if err != nil {
 		return fmt.Errorf("failed to marshal keybinds config: %w", err)
 	}
 
-	err = os.WriteFile(filePath, tomlData, 0644)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to write keybinds file %s: %w", filePath, err)
+	}
+	err = os.WriteFile(filePath, data, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write keybinds file %s: %w", filePath, err)
 	}
@@ -298,81 +665,167 @@ func LoadKeyBindings(filePath string) (KeyBindingsMap, error) {
 	defaultBindings := DefaultKeyBindingsMap()
 
 	if filePath == "" {
-		configDir, err := GetConfigDir()
+		path, err := config.FilePath(keybindsFiles...)
 		if err != nil {
 			return defaultBindings, err
 		}
-
-		filePath = filepath.Join(configDir, "keybinds.toml")
+		filePath = path
 
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			if err := SaveKeyBindings(defaultBindings, ""); err != nil {
+			if err := SaveKeyBindings(defaultBindings, filePath); err != nil {
 				return defaultBindings, fmt.Errorf("failed to create default keybinds file: %w", err)
 			}
 			return defaultBindings, nil
 		}
 	}
 
-	tomlData, err := os.ReadFile(filePath)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return defaultBindings, fmt.Errorf("failed to read keybinds file %s: %w", filePath, err)
 	}
 
-	var config KeyBindingsConfig
-	if err := toml.Unmarshal(tomlData, &config); err != nil {
+	var keybinds KeyBindingsConfig
+	if err := KeybindsMigrations.Decode(filePath, data, &keybinds); err != nil {
 		return defaultBindings, fmt.Errorf("failed to parse keybinds file %s: %w", filePath, err)
 	}
 
-	if len(config.Keybinds.Up) == 0 {
-		config.Keybinds.Up = defaultBindings.Up
+	if len(keybinds.Keybinds.Up) == 0 {
+		keybinds.Keybinds.Up = defaultBindings.Up
+	}
+	if len(keybinds.Keybinds.Down) == 0 {
+		keybinds.Keybinds.Down = defaultBindings.Down
+	}
+	if len(keybinds.Keybinds.Left) == 0 {
+		keybinds.Keybinds.Left = defaultBindings.Left
+	}
+	if len(keybinds.Keybinds.Right) == 0 {
+		keybinds.Keybinds.Right = defaultBindings.Right
+	}
+	if len(keybinds.Keybinds.PageUp) == 0 {
+		keybinds.Keybinds.PageUp = defaultBindings.PageUp
+	}
+	if len(keybinds.Keybinds.PageDown) == 0 {
+		keybinds.Keybinds.PageDown = defaultBindings.PageDown
+	}
+	if len(keybinds.Keybinds.Home) == 0 {
+		keybinds.Keybinds.Home = defaultBindings.Home
+	}
+	if len(keybinds.Keybinds.End) == 0 {
+		keybinds.Keybinds.End = defaultBindings.End
+	}
+	if len(keybinds.Keybinds.Quit) == 0 {
+		keybinds.Keybinds.Quit = defaultBindings.Quit
+	}
+	if len(keybinds.Keybinds.ToggleColor) == 0 {
+		keybinds.Keybinds.ToggleColor = defaultBindings.ToggleColor
+	}
+	if len(keybinds.Keybinds.ResetScroll) == 0 {
+		keybinds.Keybinds.ResetScroll = defaultBindings.ResetScroll
+	}
+	if len(keybinds.Keybinds.TabNext) == 0 {
+		keybinds.Keybinds.TabNext = defaultBindings.TabNext
+	}
+	if len(keybinds.Keybinds.TabPrev) == 0 {
+		keybinds.Keybinds.TabPrev = defaultBindings.TabPrev
+	}
+	if len(keybinds.Keybinds.ClearBuffer) == 0 {
+		keybinds.Keybinds.ClearBuffer = defaultBindings.ClearBuffer
+	}
+	if len(keybinds.Keybinds.ToggleWordWrap) == 0 {
+		keybinds.Keybinds.ToggleWordWrap = defaultBindings.ToggleWordWrap
+	}
+	if len(keybinds.Keybinds.ToggleTabPosition) == 0 {
+		keybinds.Keybinds.ToggleTabPosition = defaultBindings.ToggleTabPosition
+	}
+	if len(keybinds.Keybinds.FilterInclude) == 0 {
+		keybinds.Keybinds.FilterInclude = defaultBindings.FilterInclude
+	}
+	if len(keybinds.Keybinds.FilterExclude) == 0 {
+		keybinds.Keybinds.FilterExclude = defaultBindings.FilterExclude
+	}
+	if len(keybinds.Keybinds.ClearFilters) == 0 {
+		keybinds.Keybinds.ClearFilters = defaultBindings.ClearFilters
+	}
+	if len(keybinds.Keybinds.CycleLevel) == 0 {
+		keybinds.Keybinds.CycleLevel = defaultBindings.CycleLevel
+	}
+	if len(keybinds.Keybinds.NextError) == 0 {
+		keybinds.Keybinds.NextError = defaultBindings.NextError
+	}
+	if len(keybinds.Keybinds.PrevError) == 0 {
+		keybinds.Keybinds.PrevError = defaultBindings.PrevError
+	}
+	if len(keybinds.Keybinds.ToggleStructured) == 0 {
+		keybinds.Keybinds.ToggleStructured = defaultBindings.ToggleStructured
+	}
+	if len(keybinds.Keybinds.ExpandLine) == 0 {
+		keybinds.Keybinds.ExpandLine = defaultBindings.ExpandLine
+	}
+	if len(keybinds.Keybinds.FilterQuery) == 0 {
+		keybinds.Keybinds.FilterQuery = defaultBindings.FilterQuery
+	}
+	if len(keybinds.Keybinds.ToggleMerge) == 0 {
+		keybinds.Keybinds.ToggleMerge = defaultBindings.ToggleMerge
+	}
+	if len(keybinds.Keybinds.ToggleMergeOrder) == 0 {
+		keybinds.Keybinds.ToggleMergeOrder = defaultBindings.ToggleMergeOrder
+	}
+	if len(keybinds.Keybinds.SplitVertical) == 0 {
+		keybinds.Keybinds.SplitVertical = defaultBindings.SplitVertical
+	}
+	if len(keybinds.Keybinds.SplitHorizontal) == 0 {
+		keybinds.Keybinds.SplitHorizontal = defaultBindings.SplitHorizontal
+	}
+	if len(keybinds.Keybinds.ClosePane) == 0 {
+		keybinds.Keybinds.ClosePane = defaultBindings.ClosePane
 	}
-	if len(config.Keybinds.Down) == 0 {
-		config.Keybinds.Down = defaultBindings.Down
+	if len(keybinds.Keybinds.NextPane) == 0 {
+		keybinds.Keybinds.NextPane = defaultBindings.NextPane
 	}
-	if len(config.Keybinds.Left) == 0 {
-		config.Keybinds.Left = defaultBindings.Left
+	if len(keybinds.Keybinds.PrevPane) == 0 {
+		keybinds.Keybinds.PrevPane = defaultBindings.PrevPane
 	}
-	if len(config.Keybinds.Right) == 0 {
-		config.Keybinds.Right = defaultBindings.Right
+	if len(keybinds.Keybinds.ToggleDashboard) == 0 {
+		keybinds.Keybinds.ToggleDashboard = defaultBindings.ToggleDashboard
 	}
-	if len(config.Keybinds.PageUp) == 0 {
-		config.Keybinds.PageUp = defaultBindings.PageUp
+	if len(keybinds.Keybinds.DashboardSort) == 0 {
+		keybinds.Keybinds.DashboardSort = defaultBindings.DashboardSort
 	}
-	if len(config.Keybinds.PageDown) == 0 {
-		config.Keybinds.PageDown = defaultBindings.PageDown
+	if len(keybinds.Keybinds.Select) == 0 {
+		keybinds.Keybinds.Select = defaultBindings.Select
 	}
-	if len(config.Keybinds.Home) == 0 {
-		config.Keybinds.Home = defaultBindings.Home
+	if len(keybinds.Keybinds.CommandPalette) == 0 {
+		keybinds.Keybinds.CommandPalette = defaultBindings.CommandPalette
 	}
-	if len(config.Keybinds.End) == 0 {
-		config.Keybinds.End = defaultBindings.End
+	if len(keybinds.Keybinds.ToggleGroup) == 0 {
+		keybinds.Keybinds.ToggleGroup = defaultBindings.ToggleGroup
 	}
-	if len(config.Keybinds.Quit) == 0 {
-		config.Keybinds.Quit = defaultBindings.Quit
+	if len(keybinds.Keybinds.CycleTag) == 0 {
+		keybinds.Keybinds.CycleTag = defaultBindings.CycleTag
 	}
-	if len(config.Keybinds.ToggleColor) == 0 {
-		config.Keybinds.ToggleColor = defaultBindings.ToggleColor
+	if len(keybinds.Keybinds.ReconnectGroup) == 0 {
+		keybinds.Keybinds.ReconnectGroup = defaultBindings.ReconnectGroup
 	}
-	if len(config.Keybinds.ResetScroll) == 0 {
-		config.Keybinds.ResetScroll = defaultBindings.ResetScroll
+	if len(keybinds.Keybinds.BroadcastGroup) == 0 {
+		keybinds.Keybinds.BroadcastGroup = defaultBindings.BroadcastGroup
 	}
-	if len(config.Keybinds.TabNext) == 0 {
-		config.Keybinds.TabNext = defaultBindings.TabNext
+	if len(keybinds.Keybinds.ClearGroup) == 0 {
+		keybinds.Keybinds.ClearGroup = defaultBindings.ClearGroup
 	}
-	if len(config.Keybinds.TabPrev) == 0 {
-		config.Keybinds.TabPrev = defaultBindings.TabPrev
+	if len(keybinds.Keybinds.AddServer) == 0 {
+		keybinds.Keybinds.AddServer = defaultBindings.AddServer
 	}
-	if len(config.Keybinds.ClearBuffer) == 0 {
-		config.Keybinds.ClearBuffer = defaultBindings.ClearBuffer
+	if len(keybinds.Keybinds.EditServer) == 0 {
+		keybinds.Keybinds.EditServer = defaultBindings.EditServer
 	}
-	if len(config.Keybinds.ToggleWordWrap) == 0 {
-		config.Keybinds.ToggleWordWrap = defaultBindings.ToggleWordWrap
+	if len(keybinds.Keybinds.DeleteServer) == 0 {
+		keybinds.Keybinds.DeleteServer = defaultBindings.DeleteServer
 	}
-	if len(config.Keybinds.ToggleTabPosition) == 0 {
-		config.Keybinds.ToggleTabPosition = defaultBindings.ToggleTabPosition
+	if len(keybinds.Keybinds.ServerDetails) == 0 {
+		keybinds.Keybinds.ServerDetails = defaultBindings.ServerDetails
 	}
 
-	return config.Keybinds, nil
+	return keybinds.Keybinds, nil
 }
 
 func LoadKeyMap(filePath string) KeyMap {
@@ -0,0 +1,375 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/toyz/ssh-thing/logs"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+const mergedTabName = "All"
+
+var labelColors = []string{
+	"#8be9fd", // Dracula Cyan
+	"#50fa7b", // Dracula Green
+	"#ffb86c", // Dracula Orange
+	"#ff79c6", // Dracula Pink
+	"#bd93f9", // Dracula Purple
+	"#f1fa8c", // Dracula Yellow
+}
+
+type mergedLine struct {
+	source *components.TabContent
+	text   string
+	time   time.Time
+	seq    int
+}
+
+// mergedView interleaves complete lines from every server tab into a single
+// virtual tab. Lines are ordered by their parsed timestamp, falling back to
+// arrival time for lines without one, or purely by arrival.
+type mergedView struct {
+	tab *components.TabContent
+
+	mu       sync.Mutex
+	lines    []mergedLine
+	pending  map[*components.TabContent]string
+	labels   map[*components.TabContent]string
+	excluded map[*components.TabContent]bool
+	colors   map[*components.TabContent]int
+	// sources holds the source of each line shown in the tab, by the line as
+	// it was added to the tab. Labels are unique, so no two sources can add
+	// the same line.
+	sources map[string]*components.TabContent
+	// parsers and names hold each source's parser and name as they were
+	// when the source was added or last updated. Lines are added from the
+	// SSH stream goroutines while the UI goroutine replaces a tab's parser
+	// and name, so the view only reads these copies.
+	parsers map[*components.TabContent]logs.Parser
+	names   map[*components.TabContent]string
+	byTime  bool
+	seq     int
+	added   int
+}
+
+func newMergedView(sources []*components.TabContent) *mergedView {
+	v := &mergedView{
+		tab:      components.NewTabContent(mergedTabName),
+		pending:  make(map[*components.TabContent]string),
+		labels:   make(map[*components.TabContent]string),
+		excluded: make(map[*components.TabContent]bool),
+		colors:   make(map[*components.TabContent]int),
+		sources:  make(map[string]*components.TabContent),
+		parsers:  make(map[*components.TabContent]logs.Parser),
+		names:    make(map[*components.TabContent]string),
+		byTime:   true,
+	}
+
+	for _, source := range sources {
+		v.addSource(source)
+	}
+
+	parser := logs.NewCachedParser(logs.ParserFunc(v.parse), 2*components.DefaultMaxLines)
+	v.tab.Parser = parser
+	v.tab.Colorizer = v.colorize
+	return v
+}
+
+// addSource starts including a server tab's output. Each source keeps its
+// label color for as long as it is part of the view.
+func (v *mergedView) addSource(source *components.TabContent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.colors[source] = v.added
+	v.added++
+	v.parsers[source], v.names[source] = source.Parser, source.Name
+	v.setLabel(source)
+}
+
+// updateSource picks up a source's name and parser after its tab was
+// renamed or its settings were applied again. It must be called on the UI
+// goroutine, which is the only one that changes them.
+func (v *mergedView) updateSource(source *components.TabContent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.labels[source]; !ok {
+		return
+	}
+	v.parsers[source] = source.Parser
+	if v.names[source] != source.Name {
+		v.names[source] = source.Name
+		v.setLabel(source)
+	}
+	v.render()
+}
+
+// setLabel sets the label shown in front of a source's lines. Colors repeat,
+// so a source whose label would look like another's is numbered.
+func (v *mergedView) setLabel(source *components.TabContent) {
+	color := labelColors[v.colors[source]%len(labelColors)]
+	style := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(color))
+
+	delete(v.labels, source)
+	name := v.names[source]
+	for n := 2; v.hasLabel(style.Render("["+name+"]") + " "); n++ {
+		name = fmt.Sprintf("%s #%d", v.names[source], n)
+	}
+	v.labels[source] = style.Render("["+name+"]") + " "
+}
+
+func (v *mergedView) hasLabel(label string) bool {
+	for _, existing := range v.labels {
+		if existing == label {
+			return true
+		}
+	}
+	return false
+}
+
+// show returns a line as it is shown in the tab and remembers its source.
+func (v *mergedView) show(line mergedLine) string {
+	shown := v.labels[line.source] + line.text
+	v.sources[shown] = line.source
+	return shown
+}
+
+// removeSource drops a server tab and all of its lines from the view.
+func (v *mergedView) removeSource(source *components.TabContent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.labels, source)
+	delete(v.pending, source)
+	delete(v.excluded, source)
+	delete(v.colors, source)
+	delete(v.parsers, source)
+	delete(v.names, source)
+
+	lines := v.lines[:0]
+	for _, line := range v.lines {
+		if line.source != source {
+			lines = append(lines, line)
+		}
+	}
+	v.lines = lines
+	v.render()
+}
+
+func (v *mergedView) add(source *components.TabContent, output string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.labels[source]; !ok {
+		return
+	}
+
+	chunks := strings.Split(v.pending[source]+output, "\n")
+	v.pending[source] = chunks[len(chunks)-1]
+
+	// Lines that sort last are appended to the tab as they are; only a line
+	// with an earlier timestamp than ones already shown rebuilds it.
+	var appended []string
+	inserted := false
+	for _, text := range chunks[:len(chunks)-1] {
+		text = strings.TrimRight(text, "\r")
+		if strings.TrimSpace(ansi.Strip(text)) == "" {
+			continue
+		}
+
+		v.seq++
+		line := mergedLine{source: source, text: text, time: lineTime(v.parsers[source], text), seq: v.seq}
+
+		i := len(v.lines)
+		if v.byTime {
+			i = sort.Search(len(v.lines), func(i int) bool { return v.lines[i].time.After(line.time) })
+		}
+		if i == len(v.lines) {
+			v.lines = append(v.lines, line)
+			if !v.excluded[source] {
+				appended = append(appended, v.show(line))
+			}
+			continue
+		}
+		v.lines = append(v.lines, mergedLine{})
+		copy(v.lines[i+1:], v.lines[i:])
+		v.lines[i] = line
+		inserted = true
+	}
+
+	if len(v.lines) > components.DefaultMaxLines {
+		v.lines = v.lines[len(v.lines)-components.DefaultMaxLines:]
+	}
+
+	if inserted {
+		v.render()
+	} else if len(appended) > 0 {
+		v.tab.ScrollView.AppendLines(appended)
+		if len(v.sources) > 2*components.DefaultMaxLines {
+			// Forget the lines that were trimmed since the last render.
+			v.sources = make(map[string]*components.TabContent, len(v.lines))
+			for _, line := range v.lines {
+				v.show(line)
+			}
+		}
+	}
+}
+
+// lineTime returns the timestamp of a line from its source's parser or the
+// first ISO 8601 timestamp in it, or the arrival time if there is none.
+func lineTime(parser logs.Parser, text string) time.Time {
+	if parser != nil {
+		if entry, ok := parser.Parse(text); ok && entry.Time != "" {
+			if t, ok := logs.ParseTime(entry.Time); ok {
+				return t
+			}
+		}
+	}
+
+	if match := logs.TimestampPattern.FindString(ansi.Strip(text)); match != "" {
+		if t, ok := logs.ParseTime(match); ok {
+			return t
+		}
+	}
+
+	return time.Now()
+}
+
+func (v *mergedView) render() {
+	v.sources = make(map[string]*components.TabContent, len(v.lines))
+	lines := make([]string, 0, len(v.lines))
+	for _, line := range v.lines {
+		if !v.excluded[line.source] {
+			lines = append(lines, v.show(line))
+		}
+	}
+	v.tab.ScrollView.SetLines(lines)
+}
+
+// toggleOrder switches between timestamp and arrival ordering and reports
+// whether lines are now ordered by timestamp.
+func (v *mergedView) toggleOrder() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.byTime = !v.byTime
+	sort.SliceStable(v.lines, func(i, j int) bool {
+		if v.byTime {
+			return v.lines[i].time.Before(v.lines[j].time)
+		}
+		return v.lines[i].seq < v.lines[j].seq
+	})
+	v.render()
+	return v.byTime
+}
+
+// toggleSource includes or excludes a server tab from the merged view and
+// reports whether it is now included.
+func (v *mergedView) toggleSource(source *components.TabContent) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.excluded[source] = !v.excluded[source]
+	v.render()
+	return !v.excluded[source]
+}
+
+func (v *mergedView) includes(source *components.TabContent) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, known := v.labels[source]
+	return known && !v.excluded[source]
+}
+
+func (v *mergedView) status() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	included := 0
+	for source := range v.labels {
+		if !v.excluded[source] {
+			included++
+		}
+	}
+
+	order := "arrival"
+	if v.byTime {
+		order = "time"
+	}
+	return fmt.Sprintf("%d/%d servers, by %s", included, len(v.labels), order)
+}
+
+// mergedSource is the source of a merged line as the view knows it.
+type mergedSource struct {
+	tab    *components.TabContent
+	name   string
+	parser logs.Parser
+	label  string
+}
+
+// split separates a merged line into its source and original text.
+func (v *mergedView) split(line string) (mergedSource, string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	tab, ok := v.sources[line]
+	if !ok {
+		return mergedSource{}, line
+	}
+	source := mergedSource{tab: tab, name: v.names[tab], parser: v.parsers[tab], label: v.labels[tab]}
+	return source, line[len(source.label):]
+}
+
+// parse parses the original text of a merged line with its source's parser
+// and adds the source as a "server" field, so queries like server=web1 work.
+func (v *mergedView) parse(line string) (*logs.Entry, bool) {
+	source, text := v.split(line)
+	if source.parser == nil {
+		return nil, false
+	}
+
+	entry, ok := source.parser.Parse(text)
+	if !ok {
+		return nil, false
+	}
+
+	merged := *entry
+	merged.Fields = append([]logs.Field{{Key: "server", Value: source.name}}, entry.Fields...)
+	return &merged, true
+}
+
+func (v *mergedView) colorize(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		source, text := v.split(line)
+		if source.tab != nil && source.tab.Colorizer != nil {
+			lines[i] = source.label + source.tab.Colorizer(text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatter wraps a structured formatter so the server label stays in front
+// of the formatted line.
+func (v *mergedView) formatter(structured *structuredFormatter) components.LineFormatter {
+	return func(line string, expanded bool) (string, bool) {
+		source, text := v.split(line)
+		if source.parser == nil {
+			return "", false
+		}
+
+		out, ok := structured.formatter(source.parser)(text, expanded)
+		if !ok {
+			return "", false
+		}
+		return source.label + out, true
+	}
+}
@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/toyz/ssh-thing/config"
+)
+
+func mergedTestConfig(format string) *config.Config {
+	return &config.Config{Servers: []config.SSHServer{
+		{Name: "web", Host: "web.example.com", Port: 22, Password: "secret", Format: format},
+		{Name: "db", Host: "db.example.com", Port: 22, Password: "secret"},
+	}}
+}
+
+// TestMergedViewReload adds lines to the merged view from another goroutine,
+// as the SSH streams do, while reloads replace the servers' parsers. Run it
+// with -race.
+func TestMergedViewReload(t *testing.T) {
+	m, err := NewModel(mergedTestConfig("combined"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sized, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m = sized.(Model)
+	merged, web := m.merged, m.serverTabs()[0]
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			merged.add(web, fmt.Sprintf(`{"time":"2024-01-01T00:00:%02dZ","msg":"line %d"}`+"\n", i%60, i))
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		format := "combined"
+		if i%2 == 0 {
+			format = "json"
+		}
+		if m, _, err = m.applyConfig(mergedTestConfig(format), false); err != nil {
+			t.Fatal(err)
+		}
+		m.refreshTab(m.merged.tab)
+	}
+	wg.Wait()
+
+	// The last reload left the web server with a format its lines aren't in,
+	// so they are no longer parsed; loading it as JSON parses them again.
+	last := func() string {
+		m.merged.mu.Lock()
+		defer m.merged.mu.Unlock()
+		if len(m.merged.lines) == 0 {
+			t.Fatal("no lines were merged")
+		}
+		return m.merged.show(m.merged.lines[len(m.merged.lines)-1])
+	}
+	if _, ok := m.merged.parse(last()); ok {
+		t.Error("the merged view still parses with the replaced parser")
+	}
+	if m, _, err = m.applyConfig(mergedTestConfig("json"), false); err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := m.merged.parse(last()); !ok || entry.Fields[0].Value != "web" {
+		t.Errorf("parse = %+v, %v, want an entry from web", entry, ok)
+	}
+}
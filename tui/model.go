@@ -1,17 +1,21 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/logs"
 	"github.com/toyz/ssh-thing/ssh"
 	"github.com/toyz/ssh-thing/tui/components"
+	"github.com/toyz/ssh-thing/vault"
 )
 
 type Model struct {
@@ -28,20 +32,71 @@ type Model struct {
 	help         help.Model
 	statusBar    *components.StatusBar
 	config       *config.Config
+	filterInput  textinput.Model
+	filterMode   filterMode
+	filterErr    string
+	structured   *structuredFormatter
+	highlights   highlightCache
+	merged       *mergedView
+	layout       *pane
+	focused      *pane
+
+	dashboard     *components.Dashboard
+	showDashboard bool
+	dashboardSel  int
+	dashboardSort dashboardSort
+	dashboardGen  int
+
+	showPalette  bool
+	paletteInput textinput.Model
+	paletteItems []paletteItem
+	paletteSel   int
+
+	collapsed map[string]bool
+	tagFilter string
+
+	form          *serverForm
+	confirmDelete *components.TabContent
+	showDetails   bool
+
+	keybindsPath  string
+	configStamp   fileStamp
+	keybindsStamp fileStamp
+	inventoryGen  int
+	notice        string
+	noticeErr     bool
+
+	vault        *vault.Store
+	vaultInput   textinput.Model
+	vaultWaiting []*components.TabContent
+	vaultErr     string
+	unlocking    bool
 }
 
 func NewModel(cfg *config.Config, keybindsPath string) (Model, error) {
 	var tabs []string
 	var tabContents []*components.TabContent
 
-	for _, server := range cfg.Servers {
+	highlights := highlightCache{}
+	structured := newStructuredFormatter(cfg.Structured)
+	for i, server := range cfg.Servers {
 		tabs = append(tabs, server.Name)
 
-		tab := components.NewTabContent(server.Name)
-		tab.ScrollView.Append("Connecting...")
+		tab, err := newServerTab(cfg, &cfg.Servers[i], highlights, structured)
+		if err != nil {
+			return Model{}, err
+		}
 		tabContents = append(tabContents, tab)
 	}
 
+	// The merged view always exists so servers added later can join it, but
+	// its tab is only shown with more than one server.
+	merged := newMergedView(tabContents)
+	if len(tabContents) > 1 {
+		tabs = append([]string{merged.tab.Name}, tabs...)
+		tabContents = append([]*components.TabContent{merged.tab}, tabContents...)
+	}
+
 	helpModel := help.New()
 	helpModel.Styles.ShortKey = components.HelpShortKey
 	helpModel.Styles.ShortDesc = components.HelpShortDesc
@@ -50,7 +105,7 @@ func NewModel(cfg *config.Config, keybindsPath string) (Model, error) {
 	helpModel.Styles.FullDesc = components.HelpFullDesc
 	helpModel.Styles.FullSeparator = components.HelpFullSeparator
 
-	return Model{
+	m := Model{
 		tabs:         tabs,
 		tabContents:  tabContents,
 		activeTab:    0,
@@ -61,74 +116,164 @@ func NewModel(cfg *config.Config, keybindsPath string) (Model, error) {
 		help:         helpModel,
 		statusBar:    components.NewStatusBar(),
 		config:       cfg,
-	}, nil
+		filterInput:  newFilterInput(),
+		paletteInput: newPaletteInput(),
+		collapsed:    make(map[string]bool),
+		structured:   structured,
+		highlights:   highlights,
+		merged:       merged,
+		dashboard:    components.NewDashboard(),
+		keybindsPath: KeybindsPath(keybindsPath),
+		vault:        vault.New(VaultPath()),
+		vaultInput:   newVaultInput(),
+	}
+	m.configStamp = statFiles(cfg.WatchPaths())
+	m.keybindsStamp = statFile(m.keybindsPath)
+	if err := cfg.InventoryError(); err != nil {
+		m.notice, m.noticeErr = err.Error(), true
+	}
+
+	if len(tabContents) > 0 {
+		m.layout = &pane{tab: tabContents[0]}
+		m.focused = m.layout
+	}
+
+	session, err := LoadSession()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load session, using default layout: %v\n", err)
+		return m, nil
+	}
+	return m.restoreLayout(session.Layout), nil
 }
 
 type updateContentMsg struct {
-	index int
+	tab *components.TabContent
 }
 
-type sshConnectionMsg struct {
-	index  int
+// clientErrorMsg reports an error from a tab's connection after it was
+// established.
+type clientErrorMsg struct {
+	tab    *components.TabContent
 	client *ssh.Client
 	err    error
 }
 
-type connectSSHCmd struct {
-	index  int
-	server *config.SSHServer
+type sshConnectionMsg struct {
+	tab    *components.TabContent
+	client *ssh.Client
+	err    error
 }
 
-func connectSSHClient(index int, server *config.SSHServer) tea.Cmd {
+func connectSSHClient(tab *components.TabContent, store *vault.Store) tea.Cmd {
 	return func() tea.Msg {
-		client, err := ssh.NewClient(server)
+		client, err := ssh.NewClient(tab.Server, store)
 		return sshConnectionMsg{
-			index:  index,
+			tab:    tab,
 			client: client,
 			err:    err,
 		}
 	}
 }
 
-var (
-	infoPattern      = regexp.MustCompile(`(?i)(\[?INFO\]?[:|\s]+)|(INFO\s*-\s*)|(INFO\s{2,})|\bINFO\b`)
-	errorPattern     = regexp.MustCompile(`(?i)(\[?ERROR\]?[:|\s]+)|(ERROR\s*-\s*)|(ERROR\s{2,})|\bERROR\b`)
-	warnPattern      = regexp.MustCompile(`(?i)(\[?WARN(ING)?\]?[:|\s]+)|(\[?WARNING\]?[:|\s]+)|(WARN(ING)?\s*-\s*)|(WARN(ING)?\s{2,})|\bWARN(ING)?\b`)
-	debugPattern     = regexp.MustCompile(`(?i)(\[?DEBUG\]?[:|\s]+)|(DEBUG\s*-\s*)|(DEBUG\s{2,})|\bDEBUG\b`)
-	tracePattern     = regexp.MustCompile(`(?i)(\[?TRACE\]?[:|\s]+)|(TRACE\s*-\s*)|(TRACE\s{2,})|\bTRACE\b`)
-	fatalPattern     = regexp.MustCompile(`(?i)(\[?FATAL\]?[:|\s]+)|(FATAL\s*-\s*)|\bFATAL\b`)
-	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}([.,]\d+)?(Z|[+-]\d{2}:\d{2})`)
-)
-
-func (m Model) colorizeOutput(content string) string {
+func (m Model) colorizer(tab *components.TabContent) func(string) string {
 	if !m.colorize {
-		return content
+		return nil
+	}
+	return tab.Colorizer
+}
+
+func (m Model) refreshTab(tab *components.TabContent) {
+	tab.ScrollView.UpdateContent(m.colorizer(tab))
+	if !tab.ScrollView.UserScrolled() {
+		tab.ScrollView.GotoBottom()
+	}
+}
+
+// contentRowAt maps a screen position to a row of the active tab's content,
+// skipping the tab bar and the viewport border.
+func (m Model) contentRowAt(x, y int) (int, bool) {
+	top, left := 1, 1
+	if m.isSplit() {
+		// Pane title bar plus the border.
+		top, left = m.focused.y+2, m.focused.x+1
+	} else if m.verticalTabs {
+		left = m.tabBarWidth() + 1
+	} else {
+		top++
+	}
+
+	if x < left || y < top {
+		return 0, false
 	}
 
-	content = infoPattern.ReplaceAllString(content, lipgloss.NewStyle().Foreground(lipgloss.Color("#00AFFF")).Render("$0"))
-	content = errorPattern.ReplaceAllString(content, lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render("$0"))
-	content = warnPattern.ReplaceAllString(content, lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Render("$0"))
-	content = debugPattern.ReplaceAllString(content, lipgloss.NewStyle().Foreground(lipgloss.Color("#8BE9FD")).Render("$0"))
-	content = tracePattern.ReplaceAllString(content, lipgloss.NewStyle().Foreground(lipgloss.Color("#BD93F9")).Render("$0"))
-	content = fatalPattern.ReplaceAllString(content, lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Bold(true).Render("$0"))
-	content = timestampPattern.ReplaceAllString(content, lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B")).Render("$0"))
+	vp := m.tabContents[m.activeTab].ScrollView.ViewportModel()
+	if y-top >= vp.Height-vp.Style.GetVerticalFrameSize() {
+		return 0, false
+	}
+	return vp.YOffset + y - top, true
+}
 
-	return content
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	for _, tab := range m.tabContents {
+		tab.Close()
+	}
+	m.quitting = true
+	return m, tea.Quit
 }
 
 func (m Model) Init() tea.Cmd {
 	var cmds []tea.Cmd
 
-	for i, server := range m.config.Servers {
-		cmds = append(cmds, connectSSHClient(i, &server))
+	for _, tab := range m.tabContents {
+		if tab.Server != nil {
+			cmds = append(cmds, connectSSHClient(tab, m.vault))
+		}
 	}
 
+	cmds = append(cmds, watchFiles(m.config.WatchPaths(), m.keybindsPath), refreshInventory(m.config, m.inventoryGen))
 	return tea.Batch(cmds...)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	if m, ok := model.(Model); ok {
+		// Whatever is on screen after the update, such as a newly focused
+		// tab or pane or output arriving in one, counts as read.
+		m.markVisibleRead()
+	}
+	return model, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.notice = ""
+
+		if m.unlocking {
+			return m.updateVaultInput(msg)
+		}
+
+		if m.filterMode != filterNone {
+			return m.updateFilterInput(msg)
+		}
+
+		if m.showPalette {
+			return m.updatePalette(msg)
+		}
+
+		if m.form != nil {
+			return m.updateServerForm(msg)
+		}
+
+		if m.confirmDelete != nil {
+			return m.updateConfirmDelete(msg)
+		}
+
+		if m.showDetails && msg.String() == "esc" {
+			m.showDetails = false
+			return m, nil
+		}
+
 		if msg.String() == "?" {
 			m.help.ShowAll = !m.help.ShowAll
 
@@ -148,6 +293,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.showDashboard {
+			return m.updateDashboard(msg)
+		}
+
 		switch {
 		case key.Matches(msg, m.keys.Up):
 			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
@@ -188,18 +337,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, m.keys.Quit):
-			for _, tab := range m.tabContents {
-				tab.Close()
-			}
-			m.quitting = true
-			return m, tea.Quit
+			return m.quit()
 
 		case key.Matches(msg, m.keys.Right), key.Matches(msg, m.keys.TabNext):
-			m.activeTab = (m.activeTab + 1) % len(m.tabs)
-			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
-				m.tabContents[m.activeTab].ScrollView.SetUserScrolled(false)
-				m.tabContents[m.activeTab].ScrollView.GotoBottom()
-			}
+			m = m.stepTab(1)
 			// Adjust tab offset if needed
 			// This logic is simple: if activeTab is outside the current view, we might need to scroll.
 			// But we don't know the view height here easily without recalculating.
@@ -208,22 +349,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case key.Matches(msg, m.keys.Left), key.Matches(msg, m.keys.TabPrev):
-			m.activeTab = (m.activeTab - 1 + len(m.tabs)) % len(m.tabs)
-			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
-				m.tabContents[m.activeTab].ScrollView.SetUserScrolled(false)
-				m.tabContents[m.activeTab].ScrollView.GotoBottom()
-			}
+			m = m.stepTab(-1)
 			return m, nil
 
 		case key.Matches(msg, m.keys.ToggleColor):
 			m.colorize = !m.colorize
 
 			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
-				if m.colorize {
-					m.tabContents[m.activeTab].ScrollView.UpdateContent(m.colorizeOutput)
-				} else {
-					m.tabContents[m.activeTab].ScrollView.UpdateContent(nil)
-				}
+				tab := m.tabContents[m.activeTab]
+				tab.ScrollView.UpdateContent(m.colorizer(tab))
 			}
 			return m, nil
 
@@ -246,6 +380,128 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
+		case key.Matches(msg, m.keys.FilterInclude):
+			return m.startFilter(filterInclude)
+
+		case key.Matches(msg, m.keys.FilterExclude):
+			return m.startFilter(filterExclude)
+
+		case key.Matches(msg, m.keys.FilterQuery):
+			return m.startFilter(filterQuery)
+
+		case key.Matches(msg, m.keys.ClearFilters):
+			return m.clearFilters(), nil
+
+		case key.Matches(msg, m.keys.CycleLevel):
+			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
+				tab := m.tabContents[m.activeTab]
+				tab.ScrollView.SetMinLevel(tab.ScrollView.MinLevel().Next())
+				m.refreshTab(tab)
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.NextError), key.Matches(msg, m.keys.PrevError):
+			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
+				tab := m.tabContents[m.activeTab]
+				if tab.ScrollView.JumpToLevel(logs.LevelError, key.Matches(msg, m.keys.NextError)) {
+					tab.ScrollView.SetUserScrolled(!tab.ScrollView.ViewportModel().AtBottom())
+				}
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.ToggleStructured):
+			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
+				tab := m.tabContents[m.activeTab]
+				if tab.ScrollView.HasFormatter() {
+					tab.ScrollView.SetFormatter(nil)
+				} else if m.merged != nil && tab == m.merged.tab {
+					tab.ScrollView.SetFormatter(m.merged.formatter(m.structured))
+				} else {
+					tab.ScrollView.SetFormatter(m.structured.formatter(tab.Parser))
+				}
+				m.refreshTab(tab)
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.ExpandLine):
+			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
+				tab := m.tabContents[m.activeTab]
+				if id, ok := tab.ScrollView.LineAt(tab.ScrollView.ViewportModel().YOffset); ok && tab.ScrollView.HasFormatter() {
+					tab.ScrollView.ToggleExpanded(id)
+					m.refreshTab(tab)
+				}
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.ToggleMerge):
+			if m.hasMergedTab() && m.activeTab < len(m.tabContents) {
+				tab := m.tabContents[m.activeTab]
+				if tab != m.merged.tab {
+					m.merged.toggleSource(tab)
+					m.refreshTab(m.merged.tab)
+				}
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.ToggleMergeOrder):
+			if m.hasMergedTab() {
+				m.merged.toggleOrder()
+				m.refreshTab(m.merged.tab)
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.ToggleDashboard):
+			return m.toggleDashboard()
+
+		case key.Matches(msg, m.keys.CommandPalette):
+			return m.openPalette()
+
+		case key.Matches(msg, m.keys.ToggleGroup):
+			if m.activeTab < len(m.tabContents) {
+				m = m.toggleGroup(tabGroup(m.tabContents[m.activeTab]))
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.CycleTag):
+			return m.cycleTag(), nil
+
+		case key.Matches(msg, m.keys.ReconnectGroup):
+			return m.reconnectGroup()
+
+		case key.Matches(msg, m.keys.BroadcastGroup):
+			return m.startBroadcast()
+
+		case key.Matches(msg, m.keys.ClearGroup):
+			return m.clearGroup(), nil
+
+		case key.Matches(msg, m.keys.AddServer):
+			return m.openServerForm(nil)
+
+		case key.Matches(msg, m.keys.EditServer):
+			return m.openServerEditor()
+
+		case key.Matches(msg, m.keys.DeleteServer):
+			return m.confirmDeleteServer(), nil
+
+		case key.Matches(msg, m.keys.ServerDetails):
+			m.showDetails = !m.showDetails
+			return m, nil
+
+		case key.Matches(msg, m.keys.SplitVertical):
+			return m.splitPane(splitVertical), nil
+
+		case key.Matches(msg, m.keys.SplitHorizontal):
+			return m.splitPane(splitHorizontal), nil
+
+		case key.Matches(msg, m.keys.ClosePane):
+			return m.closePane(), nil
+
+		case key.Matches(msg, m.keys.NextPane):
+			return m.cyclePane(1), nil
+
+		case key.Matches(msg, m.keys.PrevPane):
+			return m.cyclePane(-1), nil
+
 		case key.Matches(msg, m.keys.ToggleTabPosition):
 			m.verticalTabs = !m.verticalTabs
 			if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
@@ -254,15 +510,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					helpHeight = len(m.keys.FullHelp())*2 + 4
 				}
 
-				tabWidth := 0
 				if m.verticalTabs {
-					for _, tab := range m.tabs {
-						w := lipgloss.Width(tab) + 4
-						if w > tabWidth {
-							tabWidth = w
-						}
-					}
-					m.tabContents[m.activeTab].ScrollView.SetSize(m.width-tabWidth, m.height-1-helpHeight)
+					m.tabContents[m.activeTab].ScrollView.SetSize(m.width-m.tabBarWidth(), m.height-1-helpHeight)
 				} else {
 					m.tabContents[m.activeTab].ScrollView.SetSize(m.width, m.height-1-helpHeight)
 				}
@@ -275,41 +524,55 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.MouseMsg:
-		if m.verticalTabs {
-			if msg.Type == tea.MouseLeft {
-				tabWidth := 0
-				for _, tab := range m.tabs {
-					w := lipgloss.Width(tab) + 6
-					if w > tabWidth {
-						tabWidth = w
-					}
+		if m.showPalette || m.form != nil {
+			return m, nil
+		}
+
+		if m.showDashboard {
+			if msg.X >= m.dashboard.X && msg.Y >= m.dashboard.Y {
+				return m.dashboardMouse(msg)
+			}
+		}
+
+		if m.isSplit() && msg.Type == tea.MouseLeft {
+			if p := m.layout.at(msg.X, msg.Y); p != nil && p != m.focused {
+				m = m.focusPane(p)
+			}
+		}
+
+		if msg.Type == tea.MouseLeft && m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
+			tab := m.tabContents[m.activeTab]
+			if row, ok := m.contentRowAt(msg.X, msg.Y); ok && tab.ScrollView.HasFormatter() {
+				if id, ok := tab.ScrollView.LineAt(row); ok {
+					tab.ScrollView.ToggleExpanded(id)
+					m.refreshTab(tab)
+					return m, nil
 				}
+			}
+		}
 
-				if msg.X < tabWidth {
-					// Calculate the clicked index based on scroll offset
+		if m.verticalTabs {
+			if msg.Type == tea.MouseLeft {
+				if msg.X < m.tabBarWidth() {
+					// Calculate the clicked row based on scroll offset
+					rows := m.tabBarRows()
 					clickedIndex := m.tabOffset + msg.Y
-					if clickedIndex >= 0 && clickedIndex < len(m.tabs) {
-						m.activeTab = clickedIndex
-						if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
-							m.tabContents[m.activeTab].ScrollView.SetUserScrolled(false)
-							m.tabContents[m.activeTab].ScrollView.GotoBottom()
+					if clickedIndex >= 0 && clickedIndex < len(rows) {
+						row := rows[clickedIndex]
+						if row.isHeader() {
+							return m.toggleGroup(row.group), nil
 						}
-						return m, nil
+						return m.selectTab(row.tab), nil
 					}
 				}
 			}
 		} else if msg.Type == tea.MouseLeft && msg.Y == 0 {
 			xPos := 0
-			for i, tab := range m.tabs {
-				tabWidth := lipgloss.Width(tab) + 4
+			for _, i := range m.tabOrder() {
+				tabWidth := lipgloss.Width(m.tabLabel(i))
 
 				if msg.X >= xPos && msg.X < xPos+tabWidth {
-					m.activeTab = i
-					if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
-						m.tabContents[m.activeTab].ScrollView.SetUserScrolled(false)
-						m.tabContents[m.activeTab].ScrollView.GotoBottom()
-					}
-					return m, nil
+					return m.selectTab(i), nil
 				}
 
 				xPos += tabWidth
@@ -334,66 +597,72 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for _, tab := range m.tabContents {
 			if !tab.HasError {
 				tab.ScrollView.SetSize(msg.Width, msg.Height-1)
-				tab.ScrollView.UpdateContent(nil)
-				if m.colorize {
-					tab.ScrollView.UpdateContent(m.colorizeOutput)
-				}
+				tab.ScrollView.UpdateContent(m.colorizer(tab))
 			}
 		}
 		return m, nil
 
+	case dashboardTickMsg:
+		if m.showDashboard && msg.gen == m.dashboardGen {
+			return m, dashboardTick(m.dashboardGen)
+		}
+		return m, nil
+
 	case updateContentMsg:
-		if msg.index < len(m.tabContents) && !m.tabContents[msg.index].HasError {
-			if m.colorize {
-				m.tabContents[msg.index].ScrollView.UpdateContent(m.colorizeOutput)
-			} else {
-				m.tabContents[msg.index].ScrollView.UpdateContent(nil)
-			}
-			if !m.tabContents[msg.index].ScrollView.UserScrolled() {
-				m.tabContents[msg.index].ScrollView.GotoBottom()
-			}
+		if !msg.tab.HasError {
+			m.refreshTab(msg.tab)
 		}
 		return m, nil
 
-	case sshConnectionMsg:
-		if msg.index < len(m.tabContents) {
-			if msg.err != nil {
-				m.tabContents[msg.index].HandleError(msg.err)
-				m.tabContents[msg.index].ScrollView.Clear()
-				m.tabContents[msg.index].ScrollView.Append("Connection failed: " + msg.err.Error())
-			} else {
-				m.tabContents[msg.index].SetClient(msg.client)
-				m.tabContents[msg.index].ScrollView.Clear()
-				m.tabContents[msg.index].ScrollView.Append("Connected to " + lipgloss.NewStyle().Bold(true).Render(m.config.Servers[msg.index].Name) + "\n")
-				m.tabContents[msg.index].ScrollView.Append("SSH Version: " + lipgloss.NewStyle().Bold(true).Render(string(msg.client.SSHClient.ServerVersion())) + "\n")
+	case clientErrorMsg:
+		if msg.tab.Client == msg.client {
+			msg.tab.HandleError(msg.err)
+		}
+		return m, nil
 
-				if len(m.config.Servers[msg.index].Commands) > 0 {
-					m.tabContents[msg.index].Client.RunCommands(m.config.Servers[msg.index].Commands)
-				}
+	case watchMsg:
+		return m.updateWatch(msg)
 
-				index := msg.index
-				go func() {
-					for {
-						select {
-						case output := <-msg.client.OutputChan:
-							m.tabContents[index].ScrollView.Append(output)
-
-							if program != nil {
-								program.Send(updateContentMsg{index: index})
-							}
-
-						case err := <-msg.client.ErrChan:
-							if err != nil {
-								m.tabContents[index].ScrollView.Append("Error: " + err.Error())
-
-								if program != nil {
-									program.Send(updateContentMsg{index: index})
-								}
-							}
-						}
-					}
-				}()
+	case vaultUnlockedMsg:
+		return m.updateVaultUnlocked(msg)
+
+	case configLoadedMsg:
+		return m.updateConfigLoaded(msg)
+
+	case inventoryMsg:
+		if msg.gen == m.inventoryGen {
+			return m, loadConfig(m.config.Path, true)
+		}
+		return m, nil
+
+	case sshConnectionMsg:
+		tab := msg.tab
+		if m.tabIndex(tab) < 0 {
+			// The server was removed while connecting.
+			if msg.client != nil {
+				msg.client.Close()
+			}
+			return m, nil
+		}
+		if errors.Is(msg.err, vault.ErrLocked) {
+			return m.waitForVault(tab)
+		}
+		if msg.err != nil {
+			tab.HandleError(msg.err)
+			tab.ScrollView.Clear()
+			tab.ScrollView.Append("Connection failed: " + msg.err.Error())
+		} else {
+			tab.SetClient(msg.client)
+			tab.Stats.Connected()
+			tab.ScrollView.Clear()
+			tab.ScrollView.Append("Connected to " + lipgloss.NewStyle().Bold(true).Render(tab.Server.Name) + "\n")
+			tab.ScrollView.Append("SSH Version: " + lipgloss.NewStyle().Bold(true).Render(string(msg.client.SSHClient.ServerVersion())) + "\n")
+
+			if len(tab.Client.Config.Commands) > 0 {
+				tab.Client.RunCommands(tab.Client.Config.Commands)
 			}
+
+			go streamClient(tab, msg.client, m.merged)
 		}
 		return m, nil
 	}
@@ -401,6 +670,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.help, cmd = m.help.Update(msg)
 
+	if m.filterMode != filterNone {
+		var inputCmd tea.Cmd
+		m.filterInput, inputCmd = m.filterInput.Update(msg)
+		cmd = tea.Batch(cmd, inputCmd)
+	}
+
+	if m.showPalette {
+		var inputCmd tea.Cmd
+		m.paletteInput, inputCmd = m.paletteInput.Update(msg)
+		cmd = tea.Batch(cmd, inputCmd)
+	}
+
+	if m.unlocking {
+		var inputCmd tea.Cmd
+		m.vaultInput, inputCmd = m.vaultInput.Update(msg)
+		cmd = tea.Batch(cmd, inputCmd)
+	}
+
 	if m.activeTab < len(m.tabContents) && !m.tabContents[m.activeTab].HasError {
 		vpModel := m.tabContents[m.activeTab].ScrollView.ViewportModel()
 		var vpCmd tea.Cmd
@@ -422,37 +709,73 @@ func (m Model) View() string {
 	// Prepare status bar data
 	var currentTab *components.TabContent
 	var serverName, status, scrollPos string
+	var statusItems []components.StatusItem
 
 	if m.activeTab < len(m.tabContents) {
 		currentTab = m.tabContents[m.activeTab]
 		serverName = currentTab.Name
-		if currentTab.HasError {
-			status = "Error"
-		} else if currentTab.Client == nil {
-			status = "Connecting"
-		} else {
-			status = "Connected"
+		status = m.tabStatus(currentTab)
+		if status == "Merged" {
+			statusItems = append(statusItems, components.StatusItem{Key: "MERGE", Value: m.merged.status()})
 		}
 		scrollPos = fmt.Sprintf("%d/%d", currentTab.ScrollView.LineCount(), components.DefaultMaxLines)
+
+		if item, ok := filterStatus(currentTab.ScrollView); ok {
+			statusItems = append(statusItems, item)
+		}
+		statusItems = append(statusItems, levelStatus(currentTab.ScrollView)...)
+		if currentTab.ScrollView.HasFormatter() {
+			statusItems = append(statusItems, components.StatusItem{Key: "VIEW", Value: "structured"})
+		}
+	}
+
+	if m.tagFilter != "" {
+		statusItems = append(statusItems, components.StatusItem{Key: "TAG", Value: m.tagFilter})
+	}
+
+	if m.showDashboard {
+		serverName, status = "Dashboard", ""
+		statusItems = []components.StatusItem{{Key: "SORT", Value: m.dashboardSort.String()}}
 	}
 
 	m.statusBar.Width = m.width
-	bar := m.statusBar.View(serverName, status, scrollPos, helpView)
+	bar := m.statusBar.View(serverName, status, scrollPos, helpView, statusItems...)
+	if prompt := m.vaultPromptView(); prompt != "" {
+		bar = lipgloss.JoinVertical(lipgloss.Left, prompt, bar)
+	} else if prompt := m.filterPromptView(); prompt != "" {
+		bar = lipgloss.JoinVertical(lipgloss.Left, prompt, bar)
+	} else if prompt := m.confirmView(); prompt != "" {
+		bar = lipgloss.JoinVertical(lipgloss.Left, prompt, bar)
+	} else if notice := m.noticeView(); notice != "" {
+		bar = lipgloss.JoinVertical(lipgloss.Left, notice, bar)
+	}
 	barHeight := lipgloss.Height(bar)
 
 	var content string
-	if currentTab != nil {
+	if m.form != nil || m.showDetails || m.showPalette || m.showDashboard || m.isSplit() {
+		x, y, width, height := 0, 1, m.width, m.height-barHeight-1
+		if m.verticalTabs {
+			tabWidth := m.tabBarWidth()
+			x, y, width, height = tabWidth, 0, m.width-tabWidth, m.height-barHeight
+		}
+
+		if m.form != nil {
+			content = m.serverFormView(width, height)
+		} else if m.showDetails {
+			content = m.detailsView(width, height)
+		} else if m.showPalette {
+			content = m.paletteView(width, height)
+		} else if m.showDashboard {
+			content = m.dashboardView(x, y, width, height)
+		} else {
+			content = m.renderPanes(m.layout, x, y, width, height)
+		}
+	} else if currentTab != nil {
 		if currentTab.HasError {
 			content = components.ErrorStyle.Render(currentTab.ErrorMsg)
 		} else {
 			if m.verticalTabs {
-				tabWidth := 0
-				for _, tab := range m.tabs {
-					w := lipgloss.Width(tab) + 6
-					if w > tabWidth {
-						tabWidth = w
-					}
-				}
+				tabWidth := m.tabBarWidth()
 
 				if currentTab.ScrollView.ViewportModel().Width != m.width-tabWidth ||
 					currentTab.ScrollView.ViewportModel().Height != m.height-barHeight {
@@ -479,11 +802,19 @@ func (m Model) View() string {
 			tabsHeight = 0
 		}
 
+		rows := m.tabBarRows()
+		activeRow := 0
+		for i, row := range rows {
+			if row.tab == m.activeTab {
+				activeRow = i
+			}
+		}
+
 		// Adjust tabOffset to ensure activeTab is visible
-		if m.activeTab < m.tabOffset {
-			m.tabOffset = m.activeTab
-		} else if m.activeTab >= m.tabOffset+tabsHeight {
-			m.tabOffset = m.activeTab - tabsHeight + 1
+		if activeRow < m.tabOffset {
+			m.tabOffset = activeRow
+		} else if activeRow >= m.tabOffset+tabsHeight {
+			m.tabOffset = activeRow - tabsHeight + 1
 		}
 		// Ensure offset is valid
 		if m.tabOffset < 0 {
@@ -491,33 +822,19 @@ func (m Model) View() string {
 		}
 
 		var verticalTabBar strings.Builder
-		// Render only visible tabs
+		// Render only visible rows
 		endIndex := m.tabOffset + tabsHeight
-		if endIndex > len(m.tabs) {
-			endIndex = len(m.tabs)
+		if endIndex > len(rows) {
+			endIndex = len(rows)
 		}
 
-		for i := m.tabOffset; i < endIndex; i++ {
-			tab := m.tabs[i]
-			icon := " "
-			if i == m.activeTab {
-				verticalTabBar.WriteString(components.ActiveTabStyle.Render(icon + tab))
-			} else {
-				verticalTabBar.WriteString(components.TabStyle.Render(icon + tab))
-			}
+		for _, row := range rows[m.tabOffset:endIndex] {
+			verticalTabBar.WriteString(m.tabBarRowLabel(row))
 			verticalTabBar.WriteString("\n")
 		}
 
-		tabWidth := 0
-		for _, tab := range m.tabs {
-			w := lipgloss.Width(tab) + 6 // Keep it safe
-			if w > tabWidth {
-				tabWidth = w
-			}
-		}
-
 		tabsView := lipgloss.NewStyle().
-			Width(tabWidth).
+			Width(m.tabBarWidth()).
 			Height(tabsHeight).
 			Render(verticalTabBar.String())
 
@@ -525,14 +842,8 @@ func (m Model) View() string {
 	} else {
 		var tabBar strings.Builder
 		xPos := 0
-		for i, tab := range m.tabs {
-			icon := " "
-			renderedTab := ""
-			if i == m.activeTab {
-				renderedTab = components.ActiveTabStyle.Render(icon + tab)
-			} else {
-				renderedTab = components.TabStyle.Render(icon + tab)
-			}
+		for _, i := range m.tabOrder() {
+			renderedTab := m.tabLabel(i)
 
 			tabWidth := lipgloss.Width(renderedTab)
 			// paddedTab := renderedTab // No extra padding needed if style handles it
@@ -0,0 +1,268 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+const paletteWidth = 72
+
+// paletteItem is an entry of the command palette: either a tab to switch to
+// or an action from the key map.
+type paletteItem struct {
+	title  string
+	detail string
+	hint   string
+
+	tab     *components.TabContent
+	binding key.Binding
+	score   int
+}
+
+func newPaletteInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "server, host, tag or action"
+	input.TextStyle = components.PromptStyle
+	input.PromptStyle = components.PromptLabelStyle
+	return input
+}
+
+func (m Model) openPalette() (Model, tea.Cmd) {
+	m.showPalette = true
+	m.paletteSel = 0
+	m.paletteInput.Reset()
+	m.paletteItems = m.paletteMatches("")
+	return m, m.paletteInput.Focus()
+}
+
+func (m Model) closePalette() Model {
+	m.showPalette = false
+	m.paletteInput.Blur()
+	return m
+}
+
+// paletteEntries lists every tab followed by every key map action.
+func (m Model) paletteEntries() []paletteItem {
+	var items []paletteItem
+
+	for _, tab := range m.tabContents {
+		item := paletteItem{title: tab.Name, hint: "tab", tab: tab}
+		if tab.Server != nil {
+			item.detail = tab.Server.Host
+			if tab.Server.User != "" {
+				item.detail = tab.Server.User + "@" + item.detail
+			}
+			if tab.Server.Group != "" {
+				item.detail += " " + tab.Server.Group
+			}
+			for _, tag := range tab.Server.Tags {
+				item.detail += " #" + tag
+			}
+		} else if m.merged != nil && tab == m.merged.tab {
+			item.detail = "all servers"
+		}
+		items = append(items, item)
+	}
+
+	for _, binding := range m.keys.Bindings() {
+		help := binding.Help()
+		if !binding.Enabled() || help == m.keys.CommandPalette.Help() {
+			continue
+		}
+		items = append(items, paletteItem{title: help.Desc, hint: help.Key, binding: binding})
+	}
+
+	return items
+}
+
+// paletteMatches returns the entries matching query, best match first.
+func (m Model) paletteMatches(query string) []paletteItem {
+	var matches []paletteItem
+	for _, item := range m.paletteEntries() {
+		score, ok := fuzzyScore(query, item.title)
+		if detailScore, detailOK := fuzzyScore(query, item.detail); detailOK && (!ok || detailScore > score) {
+			score, ok = detailScore, true
+		}
+		if ok {
+			item.score = score
+			matches = append(matches, item)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	return matches
+}
+
+func (m Model) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyEsc, key.Matches(msg, m.keys.CommandPalette):
+		return m.closePalette(), nil
+
+	case msg.Type == tea.KeyUp:
+		if m.paletteSel > 0 {
+			m.paletteSel--
+		}
+		return m, nil
+
+	case msg.Type == tea.KeyDown:
+		if m.paletteSel < len(m.paletteItems)-1 {
+			m.paletteSel++
+		}
+		return m, nil
+
+	case msg.Type == tea.KeyEnter:
+		if m.paletteSel >= len(m.paletteItems) {
+			return m, nil
+		}
+		return m.closePalette().runPaletteItem(m.paletteItems[m.paletteSel])
+	}
+
+	var cmd tea.Cmd
+	m.paletteInput, cmd = m.paletteInput.Update(msg)
+	m.paletteItems = m.paletteMatches(m.paletteInput.Value())
+	m.paletteSel = 0
+	return m, cmd
+}
+
+func (m Model) runPaletteItem(item paletteItem) (tea.Model, tea.Cmd) {
+	if item.tab != nil {
+		return m.selectTab(m.tabIndex(item.tab)), nil
+	}
+
+	// Actions run as if their first key had been pressed.
+	msg, ok := keyMsgFor(item.binding.Keys()[0])
+	if !ok {
+		return m, nil
+	}
+	return m.Update(msg)
+}
+
+// keyMsgFor builds the key message that key.Matches reports as k.
+func keyMsgFor(k string) (tea.KeyMsg, bool) {
+	msg := tea.KeyMsg{}
+	name := k
+	if strings.HasPrefix(name, "alt+") && len(name) > len("alt+") {
+		msg.Alt = true
+		name = strings.TrimPrefix(name, "alt+")
+	}
+
+	for t := tea.KeyF20; t <= tea.KeyBackspace; t++ {
+		if t == tea.KeyRunes {
+			continue
+		}
+		msg.Type = t
+		if msg.String() == k {
+			return msg, true
+		}
+	}
+
+	if utf8.RuneCountInString(name) == 1 {
+		msg.Type = tea.KeyRunes
+		msg.Runes = []rune(name)
+		return msg, msg.String() == k
+	}
+	return tea.KeyMsg{}, false
+}
+
+// fuzzyScore reports whether the runes of pattern appear in s in order,
+// ignoring case. Consecutive runes and runes at the start of a word score
+// higher, so "prdb" ranks "prod-db" above "prometheus-backup".
+func fuzzyScore(pattern, s string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	target := []rune(strings.ToLower(s))
+	score, pos, prev := 0, 0, -2
+	for _, r := range strings.ToLower(pattern) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+
+		found := false
+		for ; pos < len(target); pos++ {
+			if target[pos] != r {
+				continue
+			}
+
+			score++
+			if pos == prev+1 {
+				score += 3
+			}
+			if pos == 0 || !unicode.IsLetter(target[pos-1]) && !unicode.IsDigit(target[pos-1]) {
+				score += 2
+			}
+			prev = pos
+			pos++
+			found = true
+			break
+		}
+		if !found {
+			return 0, false
+		}
+	}
+
+	// Prefer shorter targets among otherwise equal matches.
+	return score*100 - len(target), true
+}
+
+func (m Model) paletteView(width, height int) string {
+	boxWidth := paletteWidth
+	if boxWidth > width-2 {
+		boxWidth = width - 2
+	}
+	inner := boxWidth - 4
+
+	m.paletteInput.Width = inner - lipgloss.Width(m.paletteInput.Prompt) - 1
+	lines := []string{m.paletteInput.View(), ""}
+
+	rows := height - 6
+	if rows < 1 {
+		rows = 1
+	}
+	first := 0
+	if m.paletteSel >= rows {
+		first = m.paletteSel - rows + 1
+	}
+
+	for i := first; i < len(m.paletteItems) && i < first+rows; i++ {
+		item := m.paletteItems[i]
+
+		hint := components.PaletteHintStyle.Render(item.hint)
+		text := item.title
+		if item.detail != "" {
+			text += "  " + components.PaletteHintStyle.Render(item.detail)
+		}
+		text = ansi.Truncate(text, inner-lipgloss.Width(hint)-1, "…")
+
+		gap := inner - lipgloss.Width(text) - lipgloss.Width(hint)
+		if gap < 1 {
+			gap = 1
+		}
+		row := text + strings.Repeat(" ", gap) + hint
+
+		if i == m.paletteSel {
+			row = components.PaletteSelectedStyle.Width(inner).Render(ansi.Strip(row))
+		}
+		lines = append(lines, row)
+	}
+
+	if len(m.paletteItems) == 0 {
+		lines = append(lines, components.PaletteHintStyle.Render("No matches"))
+	}
+
+	box := components.PaletteStyle.Width(boxWidth - 2).Render(strings.Join(lines, "\n"))
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Top, box)
+}
@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+type splitDir int
+
+const (
+	splitNone splitDir = iota
+	// splitHorizontal stacks the two panes on top of each other.
+	splitHorizontal
+	// splitVertical places the two panes side by side.
+	splitVertical
+)
+
+// pane is a node of the layout tree. Leaves show a tab; inner nodes split
+// their area between two children. Each leaf shows a different tab, so every
+// pane keeps its own scroll and follow state in that tab's ScrollView.
+type pane struct {
+	split    splitDir
+	children []*pane
+	parent   *pane
+	tab      *components.TabContent
+
+	// Screen area the pane was last rendered to, used for mouse handling.
+	x, y, width, height int
+}
+
+func (p *pane) leaves() []*pane {
+	if p.split == splitNone {
+		return []*pane{p}
+	}
+
+	var leaves []*pane
+	for _, child := range p.children {
+		leaves = append(leaves, child.leaves()...)
+	}
+	return leaves
+}
+
+func (p *pane) findTab(tab *components.TabContent) *pane {
+	for _, leaf := range p.leaves() {
+		if leaf.tab == tab {
+			return leaf
+		}
+	}
+	return nil
+}
+
+func (p *pane) at(x, y int) *pane {
+	for _, leaf := range p.leaves() {
+		if x >= leaf.x && x < leaf.x+leaf.width && y >= leaf.y && y < leaf.y+leaf.height {
+			return leaf
+		}
+	}
+	return nil
+}
+
+func (m Model) isSplit() bool {
+	return m.layout != nil && m.layout.split != splitNone
+}
+
+func (m Model) tabIndex(tab *components.TabContent) int {
+	for i, t := range m.tabContents {
+		if t == tab {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectTab makes a tab active and shows it in the focused pane. If the tab
+// is already shown in another pane the two panes swap tabs.
+func (m Model) selectTab(index int) Model {
+	if index < 0 || index >= len(m.tabContents) {
+		return m
+	}
+
+	m.activeTab = index
+	m.showDashboard = false
+	tab := m.tabContents[index]
+	m = m.revealTab(tab)
+	if !tab.HasError {
+		tab.ScrollView.SetUserScrolled(false)
+		tab.ScrollView.GotoBottom()
+	}
+
+	if m.focused != nil {
+		if other := m.layout.findTab(tab); other != nil && other != m.focused {
+			other.tab = m.focused.tab
+		}
+		m.focused.tab = tab
+	}
+	return m
+}
+
+func (m Model) focusPane(p *pane) Model {
+	m.focused = p
+	if i := m.tabIndex(p.tab); i >= 0 {
+		m.activeTab = i
+	}
+	return m
+}
+
+// splitPane splits the focused pane and shows the next tab that is not yet
+// visible in the new pane.
+func (m Model) splitPane(dir splitDir) Model {
+	if m.focused == nil || len(m.tabContents) == 0 {
+		return m
+	}
+
+	var next *components.TabContent
+	for i := 1; i <= len(m.tabContents); i++ {
+		candidate := m.tabContents[(m.activeTab+i)%len(m.tabContents)]
+		if m.layout.findTab(candidate) == nil {
+			next = candidate
+			break
+		}
+	}
+	if next == nil {
+		return m
+	}
+
+	leaf := m.focused
+	first := &pane{tab: leaf.tab, parent: leaf}
+	second := &pane{tab: next, parent: leaf}
+	leaf.split = dir
+	leaf.tab = nil
+	leaf.children = []*pane{first, second}
+
+	return m.focusPane(second)
+}
+
+// closePane removes the focused pane, giving its area to its sibling.
+func (m Model) closePane() Model {
+	if m.focused == nil || m.focused.parent == nil {
+		return m
+	}
+
+	parent := m.focused.parent
+	sibling := parent.children[0]
+	if sibling == m.focused {
+		sibling = parent.children[1]
+	}
+
+	parent.split = sibling.split
+	parent.tab = sibling.tab
+	parent.children = sibling.children
+	for _, child := range parent.children {
+		child.parent = parent
+	}
+
+	return m.focusPane(parent.leaves()[0])
+}
+
+func (m Model) cyclePane(delta int) Model {
+	leaves := m.layout.leaves()
+	for i, leaf := range leaves {
+		if leaf == m.focused {
+			return m.focusPane(leaves[(i+delta+len(leaves))%len(leaves)])
+		}
+	}
+	return m
+}
+
+// renderPanes draws the layout tree into the given screen area.
+func (m Model) renderPanes(p *pane, x, y, width, height int) string {
+	p.x, p.y, p.width, p.height = x, y, width, height
+
+	switch p.split {
+	case splitVertical:
+		left := width / 2
+		return lipgloss.JoinHorizontal(lipgloss.Top,
+			m.renderPanes(p.children[0], x, y, left, height),
+			m.renderPanes(p.children[1], x+left, y, width-left, height),
+		)
+	case splitHorizontal:
+		top := height / 2
+		return lipgloss.JoinVertical(lipgloss.Left,
+			m.renderPanes(p.children[0], x, y, width, top),
+			m.renderPanes(p.children[1], x, y+top, width, height-top),
+		)
+	}
+
+	titleStyle := components.TabStyle
+	if p == m.focused {
+		titleStyle = components.ActiveTabStyle
+	}
+
+	name := ""
+	if p.tab != nil {
+		name = p.tab.Name
+	}
+	title := titleStyle.Width(width).MaxWidth(width).Render(name)
+
+	body := ""
+	bodyHeight := height - 1
+	if p.tab != nil && bodyHeight > 0 {
+		if p.tab.HasError {
+			body = components.ErrorStyle.Width(width).Render(p.tab.ErrorMsg)
+		} else {
+			view := p.tab.ScrollView
+			if view.ViewportModel().Width != width || view.ViewportModel().Height != bodyHeight {
+				view.SetSize(width, bodyHeight)
+			}
+			view.SetBorder(lipgloss.RoundedBorder())
+			body = view.View()
+		}
+	}
+
+	return lipgloss.NewStyle().
+		Width(width).MaxWidth(width).
+		Height(height).MaxHeight(height).
+		Render(lipgloss.JoinVertical(lipgloss.Left, title, body))
+}
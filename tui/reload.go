@@ -0,0 +1,305 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+const reloadInterval = 2 * time.Second
+
+// fileStamp identifies a version of a watched file. The zero value means the
+// file does not exist.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	if path == "" {
+		return fileStamp{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// statFiles combines the stamps of several files and directories into one
+// that changes whenever any of them does. Directories change when files are
+// added to or removed from them.
+func statFiles(paths []string) fileStamp {
+	var stamp fileStamp
+	for _, path := range paths {
+		s := statFile(path)
+		if s.modTime.After(stamp.modTime) {
+			stamp.modTime = s.modTime
+		}
+		stamp.size += s.size
+	}
+	return stamp
+}
+
+type watchMsg struct {
+	// configPaths are the paths the config stamp was taken from.
+	configPaths []string
+	config      fileStamp
+	keybinds    fileStamp
+}
+
+// watchFiles polls the config's files and the keybinds file for changes.
+func watchFiles(configPaths []string, keybindsPath string) tea.Cmd {
+	return tea.Tick(reloadInterval, func(time.Time) tea.Msg {
+		return watchMsg{configPaths: configPaths, config: statFiles(configPaths), keybinds: statFile(keybindsPath)}
+	})
+}
+
+// KeybindsPath is the keybinds file that is loaded for path, as resolved by
+// LoadKeyBindings.
+func KeybindsPath(path string) string {
+	if path != "" {
+		return path
+	}
+	path, err := config.FilePath(keybindsFiles...)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// configLoadedMsg carries a config loaded in the background, either because
+// the servers file changed or to refresh its inventories.
+type configLoadedMsg struct {
+	cfg     *config.Config
+	err     error
+	refresh bool
+}
+
+// loadConfig loads the servers file without blocking the UI, as inventory
+// commands can take a while.
+func loadConfig(path string, refresh bool) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := config.LoadConfig(path)
+		return configLoadedMsg{cfg: cfg, err: err, refresh: refresh}
+	}
+}
+
+type inventoryMsg struct {
+	gen int
+}
+
+// refreshInventory schedules the next inventory refresh. Ticks from an
+// earlier generation are ignored, so only the latest schedule is followed.
+func refreshInventory(cfg *config.Config, gen int) tea.Cmd {
+	interval := cfg.InventoryRefresh()
+	if interval == 0 || cfg.Path == "" {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return inventoryMsg{gen: gen}
+	})
+}
+
+func (m Model) updateWatch(msg watchMsg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	// A stamp of the files before the last reload says nothing about the
+	// files loaded now.
+	if msg.config != m.configStamp && slices.Equal(msg.configPaths, m.config.WatchPaths()) {
+		m.configStamp = msg.config
+		if msg.config != (fileStamp{}) && m.config.Path != "" {
+			cmds = append(cmds, loadConfig(m.config.Path, false))
+		}
+	}
+
+	if msg.keybinds != m.keybindsStamp {
+		m.keybindsStamp = msg.keybinds
+		if msg.keybinds != (fileStamp{}) {
+			bindings, err := LoadKeyBindings(m.keybindsPath)
+			if err != nil {
+				m.notice = "Not reloading key bindings: " + err.Error()
+				m.noticeErr = true
+			} else {
+				m.keys = bindings.ToKeyMap()
+				m.notice, m.noticeErr = "Reloaded key bindings", false
+			}
+		}
+	}
+
+	cmds = append(cmds, watchFiles(m.config.WatchPaths(), m.keybindsPath))
+	return m, tea.Batch(cmds...)
+}
+
+func (m Model) updateConfigLoaded(msg configLoadedMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	err := msg.err
+	if err == nil {
+		m, cmd, err = m.applyConfig(msg.cfg, msg.refresh)
+	}
+	if err != nil {
+		if msg.refresh {
+			m.notice, m.noticeErr = "Not refreshing inventory: "+firstProblem(err), true
+		} else {
+			m.notice, m.noticeErr = "Not reloading: "+firstProblem(err), true
+		}
+	}
+
+	m.inventoryGen++
+	return m, tea.Batch(cmd, refreshInventory(m.config, m.inventoryGen))
+}
+
+// applyConfig applies the differences between the running session and a
+// freshly loaded config: tabs are added for new servers and closed for
+// removed ones, and servers whose connection settings changed are
+// reconnected. Servers are matched by name. Nothing is applied if a server's
+// settings are invalid.
+func (m Model) applyConfig(cfg *config.Config, refresh bool) (Model, tea.Cmd, error) {
+	var err error
+
+	// Inventories that failed to load keep the servers they supplied last.
+	for _, tab := range m.serverTabs() {
+		if tab.Server.Source != "" && cfg.InventoryFailed(tab.Server.Source) {
+			cfg.Servers = append(cfg.Servers, *tab.Server)
+		}
+	}
+
+	settings := make([]tabSettings, len(cfg.Servers))
+	for i := range cfg.Servers {
+		if settings[i], err = newTabSettings(cfg, &cfg.Servers[i], m.highlights); err != nil {
+			return m, nil, err
+		}
+	}
+
+	globalChanged := !reflect.DeepEqual(m.config.Highlights, cfg.Highlights) ||
+		!reflect.DeepEqual(m.config.Structured, cfg.Structured)
+
+	existing := make(map[string]*components.TabContent)
+	for _, tab := range m.serverTabs() {
+		existing[tab.Name] = tab
+	}
+	var active *components.TabContent
+	if m.activeTab < len(m.tabContents) {
+		active = m.tabContents[m.activeTab]
+	}
+
+	if !slices.Equal(m.config.WatchPaths(), cfg.WatchPaths()) {
+		// Files were included or dropped; start watching them as they are.
+		m.configStamp = statFiles(cfg.WatchPaths())
+	}
+	*m.config = *cfg
+	*m.structured = *newStructuredFormatter(cfg.Structured)
+
+	var cmds []tea.Cmd
+	var tabs []*components.TabContent
+	var added, reconnected int
+	for i := range m.config.Servers {
+		server := &m.config.Servers[i]
+
+		tab, ok := existing[server.Name]
+		if !ok {
+			tab = components.NewTabContent(server.Name)
+			tab.Server = server
+			settings[i].apply(tab, m.structured)
+			tab.ScrollView.Append("Connecting...")
+			m.merged.addSource(tab)
+			cmds = append(cmds, connectSSHClient(tab, m.vault))
+			tabs = append(tabs, tab)
+			added++
+			continue
+		}
+		delete(existing, server.Name)
+
+		old := *tab.Server
+		tab.Server = server
+		if globalChanged || old.Format != server.Format || !reflect.DeepEqual(old.Highlights, server.Highlights) {
+			settings[i].apply(tab, m.structured)
+			m.merged.updateSource(tab)
+			m.refreshTab(tab)
+		}
+		if connectionChanged(old, *server) {
+			cmds = append(cmds, m.reconnectTab(tab))
+			reconnected++
+		}
+		tabs = append(tabs, tab)
+	}
+
+	if m.hasMergedTab() {
+		tabs = append([]*components.TabContent{m.merged.tab}, tabs...)
+	}
+	m.tabContents = tabs
+
+	for _, tab := range existing {
+		tab.Close()
+		m.merged.removeSource(tab)
+		m.dropFromLayout(tab)
+		if m.confirmDelete == tab {
+			m.confirmDelete = nil
+		}
+	}
+
+	m.activeTab = 0
+	if i := m.tabIndex(active); i >= 0 {
+		m.activeTab = i
+	}
+	m = m.syncTabs()
+
+	var changes []string
+	if added > 0 {
+		changes = append(changes, fmt.Sprintf("%d added", added))
+	}
+	if len(existing) > 0 {
+		changes = append(changes, fmt.Sprintf("%d removed", len(existing)))
+	}
+	if reconnected > 0 {
+		changes = append(changes, fmt.Sprintf("%d reconnected", reconnected))
+	}
+	if len(changes) > 0 && refresh {
+		m.notice, m.noticeErr = "Refreshed inventory: "+strings.Join(changes, ", "), false
+	} else if len(changes) > 0 {
+		m.notice, m.noticeErr = "Reloaded "+m.config.Path+": "+strings.Join(changes, ", "), false
+	}
+	if err := cfg.InventoryError(); err != nil {
+		m.notice, m.noticeErr = err.Error()+"; keeping its last servers", true
+	}
+
+	return m, tea.Batch(cmds...), nil
+}
+
+// firstProblem shortens a validation error to its first problem that stopped
+// the config from loading, so it fits on one line.
+func firstProblem(err error) string {
+	var invalid *config.ValidationError
+	if !errors.As(err, &invalid) {
+		return err.Error()
+	}
+
+	var errs []config.Problem
+	for _, problem := range invalid.Problems {
+		if !problem.Warning {
+			errs = append(errs, problem)
+		}
+	}
+	if len(errs) < 2 {
+		return errs[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more problems)", errs[0], len(errs)-1)
+}
+
+func (m Model) noticeView() string {
+	if m.notice == "" {
+		return ""
+	}
+	if m.noticeErr {
+		return components.ErrorStyle.Render(m.notice)
+	}
+	return components.PromptLabelStyle.Render(m.notice)
+}
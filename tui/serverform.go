@@ -0,0 +1,416 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+const formWidth = 64
+
+const (
+	fieldName = iota
+	fieldTemplate
+	fieldHost
+	fieldUser
+	fieldPort
+	fieldKey
+	fieldPassword
+	fieldCommands
+)
+
+var formLabels = []string{"Name", "Template", "Host", "User", "Port", "Private key", "Password", "Commands"}
+
+// serverForm edits the connection settings of a new or existing server.
+type serverForm struct {
+	// tab is the server being edited, or nil when adding a server.
+	tab *components.TabContent
+
+	inputs   []textinput.Model
+	commands textarea.Model
+	focus    int
+	err      string
+
+	// initial holds the value of each field when the form was opened, to
+	// tell which ones were changed.
+	initial []string
+}
+
+func newServerForm(tab *components.TabContent) *serverForm {
+	f := &serverForm{tab: tab}
+
+	placeholders := []string{"web1", "none", "example.com", "root", "22", "~/.ssh/id_ed25519", "used when no key is set"}
+	for _, placeholder := range placeholders {
+		input := textinput.New()
+		input.Prompt = ""
+		input.Placeholder = placeholder
+		input.TextStyle = components.PromptStyle
+		f.inputs = append(f.inputs, input)
+	}
+	f.inputs[fieldPassword].EchoMode = textinput.EchoPassword
+
+	f.commands = textarea.New()
+	f.commands.Placeholder = "one command per line"
+	f.commands.ShowLineNumbers = false
+	f.commands.SetHeight(4)
+
+	if tab != nil && tab.Server != nil {
+		server := tab.Server
+		f.inputs[fieldName].SetValue(server.Name)
+		f.inputs[fieldTemplate].SetValue(server.Extends)
+		f.inputs[fieldHost].SetValue(server.Host)
+		f.inputs[fieldUser].SetValue(server.User)
+		f.inputs[fieldPort].SetValue(strconv.Itoa(server.Port))
+		f.inputs[fieldKey].SetValue(server.PrivateKeyPath)
+		f.inputs[fieldPassword].SetValue(server.Password)
+		f.commands.SetValue(strings.Join(server.Commands, "\n"))
+	}
+	f.initial = f.values()
+
+	f.setFocus(fieldName)
+	return f
+}
+
+// values returns the value of each field, indexed like formLabels.
+func (f *serverForm) values() []string {
+	var values []string
+	for _, input := range f.inputs {
+		values = append(values, input.Value())
+	}
+	return append(values, f.commands.Value())
+}
+
+func (f *serverForm) setFocus(field int) tea.Cmd {
+	f.focus = (field + len(formLabels)) % len(formLabels)
+
+	for i := range f.inputs {
+		f.inputs[i].Blur()
+	}
+	f.commands.Blur()
+
+	if f.focus == fieldCommands {
+		return f.commands.Focus()
+	}
+	return f.inputs[f.focus].Focus()
+}
+
+// server builds the server from the form. written holds what goes into the
+// server's table: the fields the user changed on top of the server being
+// edited, or those filled in for a new server. resolved is the server as it
+// will be loaded, with its template, [defaults] and built-in defaults
+// applied.
+func (f *serverForm) server(cfg *config.Config) (written, resolved config.SSHServer, err error) {
+	typed := config.SSHServer{
+		Name:           strings.TrimSpace(f.inputs[fieldName].Value()),
+		Extends:        strings.TrimSpace(f.inputs[fieldTemplate].Value()),
+		Host:           strings.TrimSpace(f.inputs[fieldHost].Value()),
+		User:           strings.TrimSpace(f.inputs[fieldUser].Value()),
+		PrivateKeyPath: strings.TrimSpace(f.inputs[fieldKey].Value()),
+		Password:       f.inputs[fieldPassword].Value(),
+	}
+
+	for _, line := range strings.Split(f.commands.Value(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			typed.Commands = append(typed.Commands, line)
+		}
+	}
+
+	if port := strings.TrimSpace(f.inputs[fieldPort].Value()); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return written, resolved, fmt.Errorf("port must be a number between 1 and 65535")
+		}
+		typed.Port = n
+	}
+
+	if typed.Name == "" {
+		return written, resolved, fmt.Errorf("name is required")
+	}
+	for i := range cfg.Servers {
+		if cfg.Servers[i].Name == typed.Name && (f.tab == nil || f.tab.Server != &cfg.Servers[i]) {
+			return written, resolved, fmt.Errorf("a server named %q already exists", typed.Name)
+		}
+	}
+
+	// Port 22 is left out when nothing would be inherited in its place, as
+	// it is the port used then anyway.
+	if typed.Port == 22 {
+		if base, err := cfg.Inherit(config.SSHServer{Extends: typed.Extends}); err == nil && base.Port == 0 {
+			typed.Port = 0
+		}
+	}
+
+	written, own := typed, typed
+	if f.tab != nil {
+		// Keep the settings the form does not edit, and the fields the user
+		// left alone, so only the changed ones are written. Inherited values
+		// are dropped from own so they are inherited again.
+		written, own = *f.tab.Server, f.tab.Server.Own()
+		values := f.values()
+		changed := func(field int) bool { return values[field] != f.initial[field] }
+		if changed(fieldName) {
+			written.Name, own.Name = typed.Name, typed.Name
+		}
+		if changed(fieldTemplate) {
+			written.Extends, own.Extends = typed.Extends, typed.Extends
+		}
+		if changed(fieldHost) {
+			written.Host, own.Host = typed.Host, typed.Host
+		}
+		if changed(fieldUser) {
+			written.User, own.User = typed.User, typed.User
+		}
+		if changed(fieldPort) {
+			written.Port, own.Port = typed.Port, typed.Port
+		}
+		if changed(fieldKey) {
+			written.PrivateKeyPath, own.PrivateKeyPath = typed.PrivateKeyPath, typed.PrivateKeyPath
+		}
+		if changed(fieldPassword) {
+			written.Password, own.Password = typed.Password, typed.Password
+		}
+		if changed(fieldCommands) {
+			written.Commands, own.Commands = typed.Commands, typed.Commands
+		}
+	}
+
+	resolved, err = cfg.Inherit(own)
+	if err != nil {
+		return written, resolved, err
+	}
+	if f.tab != nil {
+		// The server's highlight rules already include the inherited ones.
+		resolved.Highlights = f.tab.Server.Highlights
+	}
+	if err := resolved.ApplyDefaults(); err != nil {
+		return written, resolved, err
+	}
+
+	// A password command or file counts as a password.
+	switch {
+	case resolved.Host == "":
+		return written, resolved, fmt.Errorf("host is required")
+	case resolved.PrivateKeyPath == "" && resolved.Password == "" && resolved.PasswordCommand == "" && resolved.PasswordFile == "":
+		return written, resolved, fmt.Errorf("a private key or a password is required")
+	}
+	return written, resolved, nil
+}
+
+func (m Model) openServerForm(tab *components.TabContent) (Model, tea.Cmd) {
+	m.form = newServerForm(tab)
+	return m, m.form.setFocus(fieldName)
+}
+
+func (m Model) updateServerForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.form
+
+	switch msg.String() {
+	case "esc":
+		m.form = nil
+		return m, nil
+
+	case "tab":
+		return m, f.setFocus(f.focus + 1)
+
+	case "shift+tab":
+		return m, f.setFocus(f.focus - 1)
+
+	case "enter":
+		if f.focus != fieldCommands {
+			return m, f.setFocus(f.focus + 1)
+		}
+
+	case "ctrl+s":
+		return m.saveServerForm()
+	}
+
+	var cmd tea.Cmd
+	if f.focus == fieldCommands {
+		f.commands, cmd = f.commands.Update(msg)
+	} else {
+		f.inputs[f.focus], cmd = f.inputs[f.focus].Update(msg)
+	}
+	f.err = ""
+	return m, cmd
+}
+
+// saveServerForm writes the form to the servers file and applies it to the
+// running session.
+func (m Model) saveServerForm() (tea.Model, tea.Cmd) {
+	f := m.form
+
+	written, resolved, err := f.server(m.config)
+	if err != nil {
+		f.err = err.Error()
+		return m, nil
+	}
+
+	if f.tab == nil {
+		resolved.File = m.config.ServersFile()
+		if err := m.editConfig(resolved.File, func(doc *config.Document) error {
+			resolved.Table = doc.ServerCount()
+			return doc.AddServer(written)
+		}); err != nil {
+			f.err = err.Error()
+			return m, nil
+		}
+
+		m, cmd, err := m.addServer(resolved)
+		if err != nil {
+			f.err = err.Error()
+			return m, nil
+		}
+		m.form = nil
+		return m, cmd
+	}
+
+	old := *f.tab.Server
+	if err := m.editServerTable(f.tab.Server, func(doc *config.Document, table int) error {
+		return doc.UpdateServer(table, old, written)
+	}); err != nil {
+		f.err = err.Error()
+		return m, nil
+	}
+
+	m, cmd := m.updateServer(f.tab, resolved)
+	m.form = nil
+	return m, cmd
+}
+
+// confirmDeleteServer asks before deleting the active server. The last
+// server can't be deleted, as there would be nothing left to show.
+func (m Model) confirmDeleteServer() Model {
+	if m.activeTab >= len(m.tabContents) || m.tabContents[m.activeTab].Server == nil {
+		return m
+	}
+	if tab := m.tabContents[m.activeTab]; tab.Server.Generated {
+		m.notice, m.noticeErr = generatedNotice(tab.Server), true
+	} else if len(m.config.Servers) > 1 {
+		m.confirmDelete = tab
+	}
+	return m
+}
+
+// openServerEditor opens the form for the active server, unless it can't be
+// edited on its own.
+func (m Model) openServerEditor() (Model, tea.Cmd) {
+	if m.activeTab >= len(m.tabContents) || m.tabContents[m.activeTab].Server == nil {
+		return m, nil
+	}
+
+	tab := m.tabContents[m.activeTab]
+	if tab.Server.Generated {
+		m.notice, m.noticeErr = generatedNotice(tab.Server), true
+		return m, nil
+	}
+	return m.openServerForm(tab)
+}
+
+func generatedNotice(server *config.SSHServer) string {
+	if server.Source != "" {
+		return server.Name + " comes from " + server.Source + "; edit the inventory instead"
+	}
+	return server.Name + " is expanded from hosts; edit its entry in the servers file instead"
+}
+
+func (m Model) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	tab := m.confirmDelete
+	m.confirmDelete = nil
+	if msg.String() != "y" {
+		return m, nil
+	}
+
+	if err := m.editServerTable(tab.Server, func(doc *config.Document, table int) error {
+		return doc.RemoveServer(table)
+	}); err != nil {
+		m.notice, m.noticeErr = "Failed to delete server: "+err.Error(), true
+		return m, nil
+	}
+
+	return m.removeServer(tab), nil
+}
+
+func (m Model) confirmView() string {
+	if m.confirmDelete == nil {
+		return ""
+	}
+	return components.PromptLabelStyle.Render("Delete server "+m.confirmDelete.Name+" from "+m.confirmDelete.Server.File+"?") +
+		components.PromptStyle.Render(" y/N")
+}
+
+// editConfig applies an edit to one of the config's files.
+func (m Model) editConfig(file string, edit func(*config.Document) error) error {
+	if file == "" {
+		return fmt.Errorf("the config was not loaded from a file")
+	}
+	return config.EditConfigFile(file, edit)
+}
+
+// editServerTable applies an edit to the [[servers]] table a server was
+// loaded from, after checking the table still holds that server.
+func (m Model) editServerTable(server *config.SSHServer, edit func(doc *config.Document, table int) error) error {
+	if server.Generated {
+		return fmt.Errorf("%s", generatedNotice(server))
+	}
+
+	return m.editConfig(server.File, func(doc *config.Document) error {
+		name, err := doc.ServerName(server.Table)
+		if err != nil {
+			return err
+		}
+		if name != server.Name {
+			return fmt.Errorf("%s changed since it was loaded; try again after it reloads", server.File)
+		}
+		return edit(doc, server.Table)
+	})
+}
+
+func (m Model) serverFormView(width, height int) string {
+	f := m.form
+
+	boxWidth := formWidth
+	if boxWidth > width-2 {
+		boxWidth = width - 2
+	}
+	inner := boxWidth - 4
+	labelWidth := 13
+
+	title := "Add server"
+	if f.tab != nil {
+		title = "Edit " + f.tab.Name
+	}
+
+	lines := []string{components.PromptLabelStyle.Render(title), ""}
+	for i, label := range formLabels {
+		style := components.PaletteHintStyle
+		if i == f.focus {
+			style = components.PromptLabelStyle
+		}
+		label = style.Width(labelWidth).Render(label)
+
+		if i == fieldCommands {
+			f.commands.SetWidth(inner - labelWidth)
+			lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, label, f.commands.View()))
+			continue
+		}
+
+		f.inputs[i].Width = inner - labelWidth - 1
+		lines = append(lines, label+f.inputs[i].View())
+	}
+
+	lines = append(lines, "")
+	if f.err != "" {
+		lines = append(lines, components.ErrorStyle.Render(f.err))
+	}
+	lines = append(lines, components.PaletteHintStyle.Render("tab next • shift+tab previous • ctrl+s save • esc cancel"))
+
+	box := components.PaletteStyle.Width(boxWidth - 2).Render(strings.Join(lines, "\n"))
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Top, box)
+}
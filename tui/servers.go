@@ -0,0 +1,257 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/logs"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+// tabSettings are the parts of a server tab derived from the config.
+type tabSettings struct {
+	colorizer func(string) string
+	parser    logs.Parser
+	format    string
+}
+
+func newTabSettings(cfg *config.Config, server *config.SSHServer, highlights highlightCache) (tabSettings, error) {
+	h, err := highlights.newHighlighter(cfg.HighlightRules(server))
+	if err != nil {
+		return tabSettings{}, fmt.Errorf("server %s: %w", server.Name, err)
+	}
+
+	parser, err := logs.NewParser(server.Format, cfg.Structured.FieldMapping())
+	if err != nil {
+		return tabSettings{}, fmt.Errorf("server %s: %w", server.Name, err)
+	}
+
+	return tabSettings{
+		colorizer: h.Apply,
+		parser:    logs.NewCachedParser(parser, 2*components.DefaultMaxLines),
+		format:    server.Format,
+	}, nil
+}
+
+// apply sets up a tab's highlighter and parser, replacing any earlier
+// settings.
+func (s tabSettings) apply(tab *components.TabContent, structured *structuredFormatter) {
+	tab.Colorizer = s.colorizer
+	tab.Parser = s.parser
+
+	if s.format == "" {
+		if tab.ScrollView.HasFormatter() {
+			tab.ScrollView.SetFormatter(nil)
+		}
+		tab.ScrollView.SetClassifier(nil)
+		tab.Stats.SetClassifier(nil)
+		return
+	}
+
+	tab.ScrollView.SetFormatter(structured.formatter(tab.Parser))
+	tab.ScrollView.SetClassifier(parsedLevel(tab.Parser))
+	tab.Stats.SetClassifier(parsedLevel(tab.Parser))
+}
+
+// newServerTab creates the tab for a server with its highlighter and parser.
+func newServerTab(cfg *config.Config, server *config.SSHServer, highlights highlightCache, structured *structuredFormatter) (*components.TabContent, error) {
+	settings, err := newTabSettings(cfg, server, highlights)
+	if err != nil {
+		return nil, err
+	}
+
+	tab := components.NewTabContent(server.Name)
+	tab.Server = server
+	settings.apply(tab, structured)
+	tab.ScrollView.Append("Connecting...")
+	return tab, nil
+}
+
+func (m Model) hasMergedTab() bool {
+	return m.tabIndex(m.merged.tab) >= 0
+}
+
+// serverTabs returns the server tabs, which are in the same order as
+// config.Servers.
+func (m Model) serverTabs() []*components.TabContent {
+	var tabs []*components.TabContent
+	for _, tab := range m.tabContents {
+		if tab.Server != nil {
+			tabs = append(tabs, tab)
+		}
+	}
+	return tabs
+}
+
+func (m Model) serverIndex(tab *components.TabContent) int {
+	for i, t := range m.serverTabs() {
+		if t == tab {
+			return i
+		}
+	}
+	return -1
+}
+
+// syncTabs updates the tab names and server pointers after tabs or servers
+// were added or removed, and shows the merged tab only while there is more
+// than one server.
+func (m Model) syncTabs() Model {
+	var active *components.TabContent
+	if m.activeTab < len(m.tabContents) {
+		active = m.tabContents[m.activeTab]
+	}
+
+	servers := m.serverTabs()
+	for i, tab := range servers {
+		tab.Server = &m.config.Servers[i]
+	}
+
+	showMerged := len(servers) > 1
+	if showMerged != m.hasMergedTab() {
+		if showMerged {
+			m.tabContents = append([]*components.TabContent{m.merged.tab}, m.tabContents...)
+		} else {
+			m.tabContents = servers
+			m.dropFromLayout(m.merged.tab)
+		}
+	}
+
+	m.tabs = nil
+	for _, tab := range m.tabContents {
+		m.tabs = append(m.tabs, tab.Name)
+	}
+
+	m.activeTab = 0
+	if i := m.tabIndex(active); i >= 0 {
+		m.activeTab = i
+	}
+
+	if len(m.tabContents) > 0 && (m.layout == nil || m.layout.tab == nil && !m.isSplit()) {
+		m.layout = &pane{tab: m.tabContents[m.activeTab]}
+		m.focused = m.layout
+	}
+	return m
+}
+
+// dropFromLayout removes a tab from the split panes, closing the pane that
+// showed it.
+func (m *Model) dropFromLayout(tab *components.TabContent) {
+	if m.layout == nil {
+		return
+	}
+
+	p := m.layout.findTab(tab)
+	if p == nil {
+		return
+	}
+
+	if p.parent == nil {
+		p.tab = nil
+		for _, t := range m.tabContents {
+			if t != tab {
+				p.tab = t
+				break
+			}
+		}
+		return
+	}
+
+	focused := m.focused
+	m.focused = p
+	*m = m.closePane()
+	if focused != p && m.layout.findTab(focused.tab) != nil {
+		*m = m.focusPane(m.layout.findTab(focused.tab))
+	}
+}
+
+// addServer appends a server to the config and opens a connected tab for it.
+func (m Model) addServer(server config.SSHServer) (Model, tea.Cmd, error) {
+	m.config.Servers = append(m.config.Servers, server)
+	added := &m.config.Servers[len(m.config.Servers)-1]
+
+	tab, err := newServerTab(m.config, added, m.highlights, m.structured)
+	if err != nil {
+		m.config.Servers = m.config.Servers[:len(m.config.Servers)-1]
+		return m, nil, err
+	}
+
+	m.tabContents = append(m.tabContents, tab)
+	m.merged.addSource(tab)
+	m = m.syncTabs()
+	return m.selectTab(m.tabIndex(tab)), connectSSHClient(tab, m.vault), nil
+}
+
+// removeServer disconnects a server, closes its tab and removes it from the
+// config after its table was removed from the servers file. It must not be
+// used on the last server.
+func (m Model) removeServer(tab *components.TabContent) Model {
+	index := m.serverIndex(tab)
+	if index < 0 {
+		return m
+	}
+
+	tab.Close()
+	m.merged.removeSource(tab)
+	m.dropFromLayout(tab)
+
+	i := m.tabIndex(tab)
+	m.tabContents = append(m.tabContents[:i:i], m.tabContents[i+1:]...)
+	file, table := tab.Server.File, tab.Server.Table
+	m.config.Servers = append(m.config.Servers[:index:index], m.config.Servers[index+1:]...)
+	for j := range m.config.Servers {
+		if m.config.Servers[j].File == file && m.config.Servers[j].Table > table {
+			m.config.Servers[j].Table--
+		}
+	}
+
+	if m.activeTab >= i && m.activeTab > 0 {
+		m.activeTab--
+	}
+	return m.syncTabs()
+}
+
+// updateServer replaces a server's settings, renaming its tab and
+// reconnecting it when the connection settings changed.
+func (m Model) updateServer(tab *components.TabContent, updated config.SSHServer) (Model, tea.Cmd) {
+	old := *tab.Server
+	*tab.Server = updated
+
+	if old.Name != updated.Name {
+		tab.Name = updated.Name
+		m.merged.updateSource(tab)
+		m = m.syncTabs()
+	}
+
+	if connectionChanged(old, updated) {
+		return m, m.reconnectTab(tab)
+	}
+	return m, nil
+}
+
+func connectionChanged(a, b config.SSHServer) bool {
+	if a.Host != b.Host || a.User != b.User || a.Port != b.Port ||
+		a.PrivateKeyPath != b.PrivateKeyPath || a.Password != b.Password ||
+		a.PasswordCommand != b.PasswordCommand || a.PasswordFile != b.PasswordFile ||
+		a.PrivateKeyPassphrase != b.PrivateKeyPassphrase ||
+		len(a.Commands) != len(b.Commands) {
+		return true
+	}
+	for i := range a.Commands {
+		if a.Commands[i] != b.Commands[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// reconnectTab closes a server tab's connection and starts a new one.
+func (m Model) reconnectTab(tab *components.TabContent) tea.Cmd {
+	tab.Close()
+	tab.SetClient(nil)
+	tab.HasError = false
+	tab.ErrorMsg = ""
+	tab.ScrollView.Append("Reconnecting...")
+	m.refreshTab(tab)
+	return connectSSHClient(tab, m.vault)
+}
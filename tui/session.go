@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+// SessionState is UI state that is remembered between runs.
+type SessionState struct {
+	Layout *LayoutState `toml:"layout,omitempty"`
+}
+
+// LayoutState is the serialized form of the pane layout. Leaves refer to tabs
+// by name so the layout survives servers being reordered.
+type LayoutState struct {
+	Split    string        `toml:"split,omitempty"`
+	Tab      string        `toml:"tab,omitempty"`
+	Focused  bool          `toml:"focused,omitempty"`
+	Children []LayoutState `toml:"children,omitempty"`
+}
+
+// SessionPath is where the layout is remembered between runs.
+func SessionPath() (string, error) {
+	path, err := config.FilePath("session.toml")
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return path, nil
+}
+
+func LoadSession() (SessionState, error) {
+	var state SessionState
+
+	filePath, err := SessionPath()
+	if err != nil {
+		return state, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("failed to read session file %s: %w", filePath, err)
+	}
+
+	if err := toml.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse session file %s: %w", filePath, err)
+	}
+	return state, nil
+}
+
+func SaveSession(state SessionState) error {
+	filePath, err := SessionPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := toml.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to write session file %s: %w", filePath, err)
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+func (m Model) sessionState() SessionState {
+	if !m.isSplit() {
+		return SessionState{}
+	}
+	state := m.layoutState(m.layout)
+	return SessionState{Layout: &state}
+}
+
+func (m Model) layoutState(p *pane) LayoutState {
+	switch p.split {
+	case splitHorizontal, splitVertical:
+		split := "horizontal"
+		if p.split == splitVertical {
+			split = "vertical"
+		}
+		return LayoutState{
+			Split:    split,
+			Children: []LayoutState{m.layoutState(p.children[0]), m.layoutState(p.children[1])},
+		}
+	}
+
+	state := LayoutState{Focused: p == m.focused}
+	if p.tab != nil {
+		state.Tab = p.tab.Name
+	}
+	return state
+}
+
+// restoreLayout rebuilds a saved layout. Panes whose tab no longer exists, or
+// is already shown in another pane, are dropped.
+func (m Model) restoreLayout(state *LayoutState) Model {
+	if state == nil {
+		return m
+	}
+
+	byName := make(map[string]*components.TabContent)
+	for _, tab := range m.tabContents {
+		byName[tab.Name] = tab
+	}
+
+	used := make(map[*components.TabContent]bool)
+	var focused *pane
+
+	var build func(s LayoutState, parent *pane) *pane
+	build = func(s LayoutState, parent *pane) *pane {
+		if s.Split == "" {
+			tab := byName[s.Tab]
+			if tab == nil || used[tab] {
+				return nil
+			}
+			used[tab] = true
+			p := &pane{tab: tab, parent: parent}
+			if s.Focused {
+				focused = p
+			}
+			return p
+		}
+
+		if len(s.Children) != 2 {
+			return nil
+		}
+
+		p := &pane{split: splitHorizontal, parent: parent}
+		if s.Split == "vertical" {
+			p.split = splitVertical
+		}
+
+		first, second := build(s.Children[0], p), build(s.Children[1], p)
+		switch {
+		case first != nil && second != nil:
+			p.children = []*pane{first, second}
+			return p
+		case first != nil:
+			first.parent = parent
+			return first
+		case second != nil:
+			second.parent = parent
+			return second
+		}
+		return nil
+	}
+
+	root := build(*state, nil)
+	if root == nil {
+		return m
+	}
+
+	m.layout = root
+	if focused == nil {
+		focused = root.leaves()[0]
+	}
+	return m.focusPane(focused)
+}
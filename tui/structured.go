@@ -0,0 +1,145 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/logs"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+var defaultLevelColors = map[logs.Level]string{
+	logs.LevelFatal: "#FF0000",
+	logs.LevelError: "#FF5555",
+	logs.LevelWarn:  "#FFFF00",
+	logs.LevelInfo:  "#00AFFF",
+	logs.LevelDebug: "#8BE9FD",
+	logs.LevelTrace: "#BD93F9",
+}
+
+var jsonKeyPattern = regexp.MustCompile(`(?m)^(\s*)("(?:[^"\\]|\\.)*")(:)`)
+
+// structuredFormatter renders parsed log lines as "time level msg key=value"
+// and expands a single line into an indented tree on request.
+type structuredFormatter struct {
+	timeStyle   lipgloss.Style
+	keyStyle    lipgloss.Style
+	msgStyle    lipgloss.Style
+	levelStyles map[logs.Level]lipgloss.Style
+}
+
+func newStructuredFormatter(cfg config.StructuredConfig) *structuredFormatter {
+	f := &structuredFormatter{
+		timeStyle:   lipgloss.NewStyle().Foreground(lipgloss.Color(colorOr(cfg.TimeColor, "#50FA7B"))),
+		keyStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color(colorOr(cfg.KeyColor, "#6272A4"))),
+		msgStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color(colorOr(cfg.MessageColor, "#F8F8F2"))),
+		levelStyles: make(map[logs.Level]lipgloss.Style),
+	}
+
+	for level, color := range defaultLevelColors {
+		f.levelStyles[level] = lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Bold(level == logs.LevelFatal)
+	}
+	for name, color := range cfg.LevelColors {
+		if level, ok := logs.ParseLevel(name); ok {
+			f.levelStyles[level] = f.levelStyles[level].Foreground(lipgloss.Color(color))
+		}
+	}
+
+	return f
+}
+
+func colorOr(color, fallback string) string {
+	if color == "" {
+		return fallback
+	}
+	return color
+}
+
+// formatter returns a LineFormatter for lines understood by parser. Lines the
+// parser rejects are left to the colorizer.
+func (f *structuredFormatter) formatter(parser logs.Parser) components.LineFormatter {
+	return func(line string, expanded bool) (string, bool) {
+		entry, ok := parser.Parse(line)
+		if !ok {
+			return "", false
+		}
+
+		out := f.formatEntry(entry)
+		if expanded {
+			out += "\n" + f.formatTree(line, entry)
+		}
+		return out, true
+	}
+}
+
+func (f *structuredFormatter) formatTree(line string, entry *logs.Entry) string {
+	if tree, ok := logs.IndentJSON(line); ok {
+		return jsonKeyPattern.ReplaceAllStringFunc(tree, func(s string) string {
+			m := jsonKeyPattern.FindStringSubmatch(s)
+			return m[1] + f.keyStyle.Render(m[2]) + m[3]
+		})
+	}
+
+	var rows []string
+	add := func(key, value string) {
+		if value != "" {
+			rows = append(rows, "  "+f.keyStyle.Render(key+":")+" "+value)
+		}
+	}
+
+	add("time", entry.Time)
+	if entry.Level != logs.LevelNone {
+		add("level", entry.Level.String())
+	}
+	add("msg", entry.Message)
+	for _, field := range entry.Fields {
+		add(field.Key, field.Value)
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (f *structuredFormatter) formatEntry(entry *logs.Entry) string {
+	var parts []string
+
+	if entry.Time != "" {
+		parts = append(parts, f.timeStyle.Render(entry.Time))
+	}
+	if entry.Level != logs.LevelNone {
+		parts = append(parts, f.levelStyles[entry.Level].Render(padRight(entry.Level.String(), 5)))
+	}
+	if entry.Message != "" {
+		parts = append(parts, f.msgStyle.Render(entry.Message))
+	}
+	for _, field := range entry.Fields {
+		parts = append(parts, f.keyStyle.Render(field.Key+"=")+quoteValue(field.Value))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t=") {
+		return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+	}
+	return value
+}
+
+// parsedLevel classifies lines by the level field of the parsed entry,
+// falling back to pattern detection for lines the parser does not handle.
+func parsedLevel(parser logs.Parser) func(string) logs.Level {
+	return func(line string) logs.Level {
+		if entry, ok := parser.Parse(line); ok && entry.Level != logs.LevelNone {
+			return entry.Level
+		}
+		return logs.DetectLevel(line)
+	}
+}
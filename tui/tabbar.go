@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/toyz/ssh-thing/tui/components"
+)
+
+// tabBadgeWidth is the room reserved in the vertical tab bar for the status
+// icon and the unread badge.
+const tabBadgeWidth = 10
+
+// tabLabel renders a tab for the tab bar: the server icon colored by
+// connection state, the name, and a badge with the lines received since the
+// tab was last viewed, shown in red when any of them were errors.
+func (m Model) tabLabel(index int) string {
+	tab := m.tabContents[index]
+
+	style := components.TabStyle
+	if index == m.activeTab {
+		style = components.ActiveTabStyle
+	}
+	// The pieces are styled separately so each keeps the tab background.
+	plain := style.Copy().UnsetPadding()
+
+	icon := plain.Copy().Foreground(components.StatusColor(m.tabStatus(tab))).Render("")
+	label := plain.Render(" ") + icon + plain.Render(" "+tab.Name)
+
+	if stats := tab.Stats.Snapshot(); stats.Unread > 0 && !m.isVisible(tab) {
+		count := fmt.Sprint(stats.Unread)
+		if stats.Unread > 999 {
+			count = "999+"
+		}
+
+		badge := components.UnreadBadgeStyle
+		if stats.UnreadError {
+			badge = components.ErrorBadgeStyle
+			count = "!" + count
+		}
+		badge = badge.Copy().Background(style.GetBackground())
+		label += plain.Render(" ") + badge.Render(count)
+	}
+
+	return label + plain.Render(" ")
+}
+
+// tabIndent is how far tabs inside a group are indented in the vertical tab
+// bar.
+const tabIndent = "  "
+
+// tabBarRowLabel renders a row of the vertical tab bar.
+func (m Model) tabBarRowLabel(row tabBarRow) string {
+	if !row.isHeader() {
+		if row.group != "" {
+			return tabIndent + m.tabLabel(row.tab)
+		}
+		return m.tabLabel(row.tab)
+	}
+
+	arrow := "▾"
+	if m.collapsed[row.group] {
+		arrow = "▸"
+	}
+
+	count, unreadError := 0, false
+	for _, tab := range m.tabContents {
+		if tabGroup(tab) == row.group && !m.tabFiltered(tab) {
+			count++
+			unreadError = unreadError || tab.Stats.Snapshot().UnreadError && !m.isVisible(tab)
+		}
+	}
+
+	label := components.TabGroupStyle.Render(fmt.Sprintf("%s %s (%d)", arrow, row.group, count))
+	if unreadError && m.collapsed[row.group] {
+		label += " " + components.ErrorBadgeStyle.Render("!")
+	}
+	return label
+}
+
+// tabBarWidth is the width of the vertical tab bar.
+func (m Model) tabBarWidth() int {
+	width := 0
+	for _, tab := range m.tabContents {
+		w := lipgloss.Width(tab.Name) + tabBadgeWidth
+		if group := tabGroup(tab); group != "" {
+			w += len(tabIndent)
+			if header := lipgloss.Width(group) + 10; header > w {
+				w = header
+			}
+		}
+		if w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// isVisible reports whether a tab is currently on screen, either as the
+// active tab or in one of the split panes, and not covered by an overlay.
+func (m Model) isVisible(tab *components.TabContent) bool {
+	if m.showDashboard || m.showPalette || m.form != nil || m.showDetails {
+		return false
+	}
+	if m.isSplit() {
+		return m.layout.findTab(tab) != nil
+	}
+	return m.activeTab < len(m.tabContents) && m.tabContents[m.activeTab] == tab
+}
+
+// markVisibleRead clears the unread badge of every tab on screen.
+func (m Model) markVisibleRead() {
+	for _, tab := range m.tabContents {
+		if m.isVisible(tab) {
+			tab.Stats.MarkRead()
+		}
+	}
+}
@@ -1,14 +1,19 @@
 package tui
 
 import (
+	"fmt"
+	"os"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/ssh"
+	"github.com/toyz/ssh-thing/tui/components"
 )
 
 var program *tea.Program
 
 func startClientCommands(model *Model) {
-	for i, tab := range model.tabContents {
+	for _, tab := range model.tabContents {
 		if tab.Client == nil || tab.HasError {
 			continue
 		}
@@ -20,24 +25,45 @@ func startClientCommands(model *Model) {
 			tab.Client.RunCommands(tab.Client.Config.Commands)
 		}
 
-		index := i
-		go func() {
-			for {
-				select {
-				case output := <-tab.Client.OutputChan:
-					tab.ScrollView.Append(output)
-
-					if program != nil {
-						program.Send(updateContentMsg{index: index})
-					}
-				case err := <-tab.Client.ErrChan:
-					tab.HandleError(err)
-					if program != nil {
-						program.Send(updateContentMsg{index: index})
-					}
+		go streamClient(tab, tab.Client, model.merged)
+	}
+}
+
+// streamClient forwards a client's output to its tab, and to the merged view
+// when there is one, until the client is closed. Errors are handed to Update
+// so the tab is only changed there.
+func streamClient(tab *components.TabContent, client *ssh.Client, merged *mergedView) {
+	for {
+		select {
+		case <-client.Done():
+			return
+
+		case output := <-client.OutputChan:
+			select {
+			case <-client.Done():
+				// The tab has already moved on to a new client.
+				return
+			default:
+			}
+
+			tab.ScrollView.Append(output)
+			tab.Stats.Record(output)
+			if merged != nil {
+				merged.add(tab, output)
+			}
+
+			if program != nil {
+				program.Send(updateContentMsg{tab: tab})
+				if merged != nil {
+					program.Send(updateContentMsg{tab: merged.tab})
 				}
 			}
-		}()
+
+		case err := <-client.ErrChan:
+			if err != nil && program != nil {
+				program.Send(clientErrorMsg{tab: tab, client: client, err: err})
+			}
+		}
 	}
 }
 
@@ -52,9 +78,16 @@ func StartTUI(cfg *config.Config, keybindsPath string) error {
 
 	startClientCommands(&model)
 
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+	if err != nil {
 		return err
 	}
 
+	if m, ok := final.(Model); ok {
+		if err := SaveSession(m.sessionState()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save session: %v\n", err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/toyz/ssh-thing/config"
+	"github.com/toyz/ssh-thing/tui/components"
+	"github.com/toyz/ssh-thing/vault"
+)
+
+// VaultPath is where the vault is kept, found like the other config files.
+func VaultPath() string {
+	path, err := config.FilePath(vault.FileName)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func newVaultInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "vault passphrase: "
+	input.EchoMode = textinput.EchoPassword
+	input.TextStyle = components.PromptStyle
+	input.PromptStyle = components.PromptLabelStyle
+	return input
+}
+
+type vaultUnlockedMsg struct {
+	err error
+}
+
+// waitForVault holds a tab whose server needs a secret from the locked
+// vault and asks for the passphrase. Every waiting tab connects once the
+// vault is unlocked, so the passphrase is asked for once per run.
+func (m Model) waitForVault(tab *components.TabContent) (Model, tea.Cmd) {
+	if m.vault.Unlocked() {
+		// The vault was unlocked while this tab was connecting.
+		return m, connectSSHClient(tab, m.vault)
+	}
+
+	tab.ScrollView.Clear()
+	tab.ScrollView.Append("Waiting for the vault to be unlocked...")
+	m.vaultWaiting = append(m.vaultWaiting, tab)
+	if m.unlocking {
+		return m, nil
+	}
+
+	m.unlocking = true
+	m.vaultErr = ""
+	m.vaultInput.Reset()
+	return m, m.vaultInput.Focus()
+}
+
+func (m Model) updateVaultInput(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.unlocking = false
+		m.vaultInput.Blur()
+		for _, tab := range m.vaultWaiting {
+			tab.HandleError(vault.ErrLocked)
+			tab.ScrollView.Clear()
+			tab.ScrollView.Append("Connection failed: " + vault.ErrLocked.Error())
+		}
+		m.vaultWaiting = nil
+		return m, nil
+
+	case tea.KeyEnter:
+		passphrase := m.vaultInput.Value()
+		if passphrase == "" {
+			return m, nil
+		}
+		m.vaultInput.Reset()
+		m.vaultErr = ""
+
+		store := m.vault
+		return m, func() tea.Msg {
+			return vaultUnlockedMsg{err: store.Unlock(passphrase)}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.vaultInput, cmd = m.vaultInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) updateVaultUnlocked(msg vaultUnlockedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		m.vaultErr = msg.err.Error()
+		return m, nil
+	}
+
+	m.unlocking = false
+	m.vaultInput.Blur()
+
+	var cmds []tea.Cmd
+	for _, tab := range m.vaultWaiting {
+		if m.tabIndex(tab) >= 0 {
+			tab.ScrollView.Append("Connecting...")
+			cmds = append(cmds, connectSSHClient(tab, m.vault))
+		}
+	}
+	m.vaultWaiting = nil
+	m.notice, m.noticeErr = "Vault unlocked", false
+	return m, tea.Batch(cmds...)
+}
+
+func (m Model) vaultPromptView() string {
+	if !m.unlocking {
+		return ""
+	}
+
+	m.vaultInput.Width = m.width - len(m.vaultInput.Prompt) - 1
+	view := m.vaultInput.View()
+	if m.vaultErr != "" {
+		view += " " + components.ErrorStyle.Render(m.vaultErr)
+	}
+	return view
+}
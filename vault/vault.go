@@ -0,0 +1,300 @@
+// Package vault keeps passwords and key passphrases in a local file
+// encrypted with a master passphrase, for servers that refer to them as
+// vault:<name>.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/toyz/ssh-thing/util"
+	"golang.org/x/crypto/scrypt"
+)
+
+// FileName is the name of the vault file in the config directory.
+const FileName = "vault.json"
+
+var (
+	// ErrLocked is returned when a secret is looked up before the vault
+	// was unlocked.
+	ErrLocked = errors.New("the vault is locked")
+
+	// ErrWrongPassphrase is returned when the vault can't be decrypted
+	// with the passphrase given.
+	ErrWrongPassphrase = errors.New("wrong vault passphrase")
+)
+
+// scrypt parameters for new vaults. Existing vaults keep the parameters they
+// were written with.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keySize = 32
+
+	// saltSize is the length of new salts and the shortest one accepted.
+	saltSize = 16
+)
+
+// Limits for the scrypt parameters read from a vault file, so a damaged or
+// crafted file can't make Unlock use gigabytes of memory or run for hours.
+const (
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+
+	// maxScryptMemory is the most memory scrypt may use, 128*N*r bytes.
+	maxScryptMemory = 1 << 30
+)
+
+// file is the on-disk form of the vault. The secrets are encrypted with
+// AES-256-GCM using a key derived from the passphrase with scrypt.
+type file struct {
+	Version    int    `json:"version"`
+	KDF        kdf    `json:"kdf"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type kdf struct {
+	Name string `json:"name"`
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+// Store is a vault file. It starts locked; Unlock or Create decrypt it and
+// keep the secrets in memory until the program exits.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	kdf     kdf
+	key     []byte
+	secrets map[string]string
+}
+
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Exists reports whether the vault file has been created.
+func (s *Store) Exists() bool {
+	if s.path == "" {
+		return false
+	}
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+func (s *Store) Unlocked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.key != nil
+}
+
+// Unlock decrypts the vault with passphrase.
+func (s *Store) Unlock(passphrase string) error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read vault %s: %w", s.path, err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("failed to parse vault %s: %w", s.path, err)
+	}
+	if f.Version != 1 || f.KDF.Name != "scrypt" {
+		return fmt.Errorf("vault %s has unsupported version %d", s.path, f.Version)
+	}
+	if err := f.KDF.check(); err != nil {
+		return fmt.Errorf("vault %s is invalid: %w", s.path, err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), f.KDF.Salt, f.KDF.N, f.KDF.R, f.KDF.P, keySize)
+	if err != nil {
+		return fmt.Errorf("failed to derive vault key: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	if len(f.Nonce) != gcm.NonceSize() {
+		return fmt.Errorf("vault %s is invalid: the nonce is %d bytes instead of %d", s.path, len(f.Nonce), gcm.NonceSize())
+	}
+	plaintext, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("failed to parse vault %s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kdf, s.key, s.secrets = f.KDF, key, secrets
+	return nil
+}
+
+// Create starts an empty vault protected by passphrase. It is written on
+// the first Set.
+func (s *Store) Create(passphrase string) error {
+	if s.Exists() {
+		return fmt.Errorf("vault %s already exists", s.path)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	params := kdf{Name: "scrypt", Salt: salt, N: scryptN, R: scryptR, P: scryptP}
+
+	key, err := scrypt.Key([]byte(passphrase), params.Salt, params.N, params.R, params.P, keySize)
+	if err != nil {
+		return fmt.Errorf("failed to derive vault key: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kdf, s.key, s.secrets = params, key, make(map[string]string)
+	return nil
+}
+
+// Secret returns the secret stored under name.
+func (s *Store) Secret(name string) (string, error) {
+	if !s.Unlocked() && !s.Exists() {
+		return "", fmt.Errorf("no vault at %s; add secrets with ssh-thing vault set", s.path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return "", ErrLocked
+	}
+	secret, ok := s.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("no secret named %q in the vault", name)
+	}
+	return secret, nil
+}
+
+// Names returns the names of the stored secrets in order.
+func (s *Store) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set stores a secret and writes the vault.
+func (s *Store) Set(name, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return ErrLocked
+	}
+
+	s.secrets[name] = secret
+	return s.save()
+}
+
+// Delete removes a secret and writes the vault.
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return ErrLocked
+	}
+	if _, ok := s.secrets[name]; !ok {
+		return fmt.Errorf("no secret named %q in the vault", name)
+	}
+
+	delete(s.secrets, name)
+	return s.save()
+}
+
+// save encrypts the secrets with a fresh nonce and replaces the vault file
+// atomically. The file is only readable by its owner.
+func (s *Store) save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(file{
+		Version:    1,
+		KDF:        s.kdf,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to write vault %s: %w", s.path, err)
+	}
+	if err := util.WriteFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write vault %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// check reports parameters that scrypt rejects or that are beyond the
+// limits, and salts too short to protect the passphrase.
+func (k kdf) check() error {
+	if len(k.Salt) < saltSize {
+		return fmt.Errorf("the salt is %d bytes, fewer than %d", len(k.Salt), saltSize)
+	}
+	if k.N < 2 || k.N&(k.N-1) != 0 || k.N > maxScryptN {
+		return fmt.Errorf("scrypt N must be a power of two up to %d, not %d", maxScryptN, k.N)
+	}
+	if k.R < 1 || k.R > maxScryptR {
+		return fmt.Errorf("scrypt r must be between 1 and %d, not %d", maxScryptR, k.R)
+	}
+	if k.P < 1 || k.P > maxScryptP {
+		return fmt.Errorf("scrypt p must be between 1 and %d, not %d", maxScryptP, k.P)
+	}
+	if 128*k.N*k.R > maxScryptMemory {
+		return fmt.Errorf("scrypt N=%d and r=%d need more than %d MiB of memory", k.N, k.R, maxScryptMemory>>20)
+	}
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,143 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const passphrase = "correct horse battery staple"
+
+// newVault creates a vault in a temporary directory holding the secrets.
+func newVault(t *testing.T, secrets map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+
+	store := New(path)
+	if err := store.Create(passphrase); err != nil {
+		t.Fatal(err)
+	}
+	for name, secret := range secrets {
+		if err := store.Set(name, secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// editVault rewrites the vault file at path after applying edit to it.
+func editVault(t *testing.T, path string, edit func(*file)) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	edit(&f)
+	if data, err = json.Marshal(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	path := newVault(t, map[string]string{"db": "hunter2", "web": "s3cret"})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("vault permissions = %o, want 600", perm)
+	}
+
+	store := New(path)
+	if _, err := store.Secret("db"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Secret before Unlock = %v, want ErrLocked", err)
+	}
+	if err := store.Unlock(passphrase); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"db": "hunter2", "web": "s3cret"} {
+		if got, err := store.Secret(name); err != nil || got != want {
+			t.Errorf("Secret(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	if err := store.Delete("web"); err != nil {
+		t.Fatal(err)
+	}
+	reopened := New(path)
+	if err := reopened.Unlock(passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if names := reopened.Names(); len(names) != 1 || names[0] != "db" {
+		t.Errorf("Names after Delete = %v, want [db]", names)
+	}
+	if _, err := reopened.Secret("web"); err == nil {
+		t.Error("Secret of a deleted name succeeded")
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	path := newVault(t, map[string]string{"db": "hunter2"})
+
+	store := New(path)
+	if err := store.Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Unlock = %v, want ErrWrongPassphrase", err)
+	}
+	if store.Unlocked() {
+		t.Error("vault is unlocked after a wrong passphrase")
+	}
+}
+
+func TestInvalidFile(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*file)
+		// want is ErrWrongPassphrase, or otherwise a part of the error.
+		want string
+	}{
+		{"tampered ciphertext", func(f *file) { f.Ciphertext[0] ^= 1 }, ""},
+		{"tampered nonce", func(f *file) { f.Nonce[0] ^= 1 }, ""},
+		{"short nonce", func(f *file) { f.Nonce = f.Nonce[:8] }, "nonce is 8 bytes"},
+		{"missing nonce", func(f *file) { f.Nonce = nil }, "nonce is 0 bytes"},
+		{"N too large", func(f *file) { f.KDF.N = 1 << 30 }, "scrypt N"},
+		{"N not a power of two", func(f *file) { f.KDF.N = 1000 }, "scrypt N"},
+		{"N zero", func(f *file) { f.KDF.N = 0 }, "scrypt N"},
+		{"r zero", func(f *file) { f.KDF.R = 0 }, "scrypt r"},
+		{"r too large", func(f *file) { f.KDF.R = 64 }, "scrypt r"},
+		{"p zero", func(f *file) { f.KDF.P = 0 }, "scrypt p"},
+		{"p too large", func(f *file) { f.KDF.P = 1 << 20 }, "scrypt p"},
+		{"too much memory", func(f *file) { f.KDF.N, f.KDF.R = 1<<20, 16 }, "MiB of memory"},
+		{"empty salt", func(f *file) { f.KDF.Salt = nil }, "salt is 0 bytes"},
+		{"short salt", func(f *file) { f.KDF.Salt = f.KDF.Salt[:1] }, "salt is 1 bytes"},
+		{"unknown version", func(f *file) { f.Version = 2 }, "unsupported version"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := newVault(t, map[string]string{"db": "hunter2"})
+			editVault(t, path, tc.edit)
+
+			err := New(path).Unlock(passphrase)
+			if tc.want == "" {
+				if !errors.Is(err, ErrWrongPassphrase) {
+					t.Fatalf("Unlock = %v, want ErrWrongPassphrase", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Unlock = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}